@@ -8,6 +8,7 @@ export interface Size {
 }
 
 export interface StickerDataDto{
+  title?: string,
   productImage: string,
   size: Size
 }
@@ -17,8 +18,8 @@ interface GetStickerDataRequest {
 }
 
 // TODO: Combine position with size, so we can share the interface.
-// TODO: Include z-index (order) to save as well.
 
+// Positions are in inches from the top-left corner of the device surface.
 export interface Position {
   x: number,
   y: number
@@ -26,18 +27,58 @@ export interface Position {
 
 export interface SaveStickerData {
   stickerId: string
+  title?: string,
   url: string,
   size: Size,
-  position: Position
+  position: Position,
+  zIndex: number
 }
 
 export interface SessionDataDto {
-  stickers: SaveStickerData[]
+  session: SessionDto,
+  stickers: SaveStickerData[],
+  deviceId: string,
+  deviceVariant?: string
 }
 
 export interface SaveSessionDataRequest {
   sessionId: string,
-  stickers: SaveStickerData[]
+  stickers: SaveStickerData[],
+  deviceId?: string,
+  deviceVariant?: string
+}
+
+export interface KeepOutZoneDto {
+  name: string,
+  polygon: Position[]
+}
+
+export interface DeviceVariantDto {
+  id: string,
+  name: string,
+  color: string,
+  backgroundImage?: string
+}
+
+// Lengths and polygon coordinates are in inches from the top-left corner of
+// the lid.
+export interface DeviceDto {
+  id: string,
+  name: string,
+  lidWidth: number,
+  lidHeight: number,
+  surface: Position[],
+  keepOutZones: KeepOutZoneDto[],
+  variants: DeviceVariantDto[]
+}
+
+interface DevicesResponse {
+  devices: DeviceDto[]
+}
+
+export async function listDevices(): Promise<DeviceDto[]> {
+  const response = await get<DevicesResponse>('/devices');
+  return response.devices;
 }
 
 export async function getStickerData(url: string): Promise<StickerDataDto> {
@@ -47,11 +88,138 @@ export async function getStickerData(url: string): Promise<StickerDataDto> {
   return response;
 }
 
-export async function saveSession(sessionData: SaveSessionDataRequest) {
-  await post<SaveSessionDataRequest, null>('/save-session', sessionData);
+export interface SessionDto {
+  id: string,
+  version: number
+}
+
+// Tokens are only returned when a session is created. The edit token also
+// grants view access.
+export interface SessionTokensDto {
+  viewToken?: string,
+  editToken?: string
+}
+
+export interface CreatedSessionDto extends SessionDto {
+  tokens: SessionTokensDto
+}
+
+// The unlock token is only needed for sessions with a passcode.
+function authHeaders(token: string, unlockToken = ''): Record<string, string> {
+  return {
+    ...(token ? { Authorization: `Bearer ${token}` } : {}),
+    ...(unlockToken ? { 'X-Unlock-Token': unlockToken } : {})
+  };
+}
+
+export async function createSession(): Promise<CreatedSessionDto> {
+  const response = await post<void, CreatedSessionDto>('/sessions');
+  return response;
+}
+
+interface SaveSessionResponse {
+  status: string,
+  version: number
+}
+
+// saveSession only succeeds if the session is still at the given version and
+// returns the new one. A 412 ApiError means someone else saved first.
+export async function saveSession(sessionData: SaveSessionDataRequest, version: number, token: string, unlockToken = ''): Promise<number> {
+  const response = await post<SaveSessionDataRequest, SaveSessionResponse>(
+    '/v2/save-session', sessionData, { 'If-Match': `"${version}"`, ...authHeaders(token, unlockToken) });
+  return response.version;
+}
+
+// getSession fails with a 423 ApiError while the session has a passcode and
+// no valid unlock token is sent.
+export async function getSession(sessionId: string, token: string, unlockToken = ''): Promise<SessionDataDto> {
+  const response = await get<SessionDataDto>('/v2/get-session', { sessionId }, authHeaders(token, unlockToken));
+  return response;
+}
+
+interface UnlockSessionRequest {
+  passcode: string
 }
 
-export async function getSession(sessionId: string): Promise<SessionDataDto> {
-  const response = await get<SessionDataDto>('/get-session', { sessionId });
+export interface UnlockSessionDto {
+  unlockToken: string,
+  expiresAt: string
+}
+
+// unlockSession exchanges the passcode for an unlock token. A 401 ApiError
+// means the passcode was wrong and a 429 that there were too many attempts.
+export async function unlockSession(sessionId: string, passcode: string, token: string): Promise<UnlockSessionDto> {
+  const response = await post<UnlockSessionRequest, UnlockSessionDto>(
+    `/sessions/${encodeURIComponent(sessionId)}/unlock`, { passcode }, authHeaders(token));
   return response;
-}
\ No newline at end of file
+}
+
+export interface UserDto {
+  id: string,
+  email: string,
+  displayName: string
+}
+
+interface LoginRequest {
+  email: string,
+  password: string
+}
+
+interface SignupRequest extends LoginRequest {
+  displayName: string
+}
+
+// login, signup and logout set or clear the login cookie; getMe fails with a
+// 401 ApiError when there is no login.
+export async function login(email: string, password: string): Promise<UserDto> {
+  const response = await post<LoginRequest, UserDto>('/login', { email, password });
+  return response;
+}
+
+export async function signup(email: string, password: string, displayName: string): Promise<UserDto> {
+  const response = await post<SignupRequest, UserDto>('/signup', { email, password, displayName });
+  return response;
+}
+
+export async function logout(): Promise<void> {
+  await post('/logout');
+}
+
+export async function getMe(): Promise<UserDto> {
+  const response = await get<UserDto>('/me');
+  return response;
+}
+
+// Library items are stickers a logged-in user saved for reuse. Size is in
+// inches. The server works out the image hash and palette itself.
+export interface LibraryItemDto {
+  id: number,
+  title: string,
+  notes: string,
+  tags: string[],
+  url: string,
+  size: Size
+}
+
+interface LibraryItemListDto {
+  items: LibraryItemDto[],
+  nextCursor?: string
+}
+
+export interface SaveLibraryItemRequest {
+  title: string,
+  url: string,
+  size: Size
+}
+
+// listLibrary and saveLibraryItem fail with a 401 ApiError when there is no
+// login.
+export async function listLibrary(query = ''): Promise<LibraryItemDto[]> {
+  const response = await get<LibraryItemListDto>('/library', query ? { q: query } : undefined);
+  return response.items;
+}
+
+export async function saveLibraryItem(item: SaveLibraryItemRequest): Promise<LibraryItemDto> {
+  const response = await post<SaveLibraryItemRequest, LibraryItemDto>('/library', item);
+  return response;
+}
@@ -1,6 +1,12 @@
 import { BASE_API } from '@/shared/const';
 
-export async function get<TResponse = unknown>(path: string, params?: Record<string, string>): Promise<TResponse> {
+export class ApiError extends Error {
+  constructor(message: string, public status: number) {
+    super(message);
+  }
+}
+
+export async function get<TResponse = unknown>(path: string, params?: Record<string, string>, headers?: Record<string, string>): Promise<TResponse> {
   let url = `${BASE_API}${path}`;
   
   if (params) {
@@ -11,31 +17,40 @@ export async function get<TResponse = unknown>(path: string, params?: Record<str
     url += `?${searchParams.toString()}`;
   }
   
+  // Credentials carry the login cookie, which the API is on another site for.
   const fetchOptions: RequestInit = {
     method: 'GET',
-    headers: { 'Content-Type': 'application/json' },
+    headers: { 'Content-Type': 'application/json', ...headers },
+    credentials: 'include',
   };
 
   const response = await fetch(url, fetchOptions);
   if (!response.ok) {
     const errorData = await response.json().catch(() => ({}));
-    throw new Error(errorData.error || 'Request failed');
+    throw new ApiError(errorData.error || 'Request failed', response.status);
+  }
+  if (response.status === 204) {
+    return undefined as TResponse;
   }
   return response.json();
 }
 
-export async function post<TBody = void, TResponse = unknown>(path: string, body?: TBody): Promise<TResponse> {
+export async function post<TBody = void, TResponse = unknown>(path: string, body?: TBody, headers?: Record<string, string>): Promise<TResponse> {
   const url = `${BASE_API}${path}`;
   const fetchOptions: RequestInit = {
     method: 'POST',
-    headers: { 'Content-Type': 'application/json' },
+    headers: { 'Content-Type': 'application/json', ...headers },
     body: body ? JSON.stringify(body) : undefined,
+    credentials: 'include',
   };
 
   const response = await fetch(url, fetchOptions);
   if (!response.ok) {
     const errorData = await response.json().catch(() => ({}));
-    throw new Error(errorData.error || 'Request failed');
+    throw new ApiError(errorData.error || 'Request failed', response.status);
+  }
+  if (response.status === 204) {
+    return undefined as TResponse;
   }
   return response.json();
 }
@@ -2,6 +2,7 @@
 
 import { useRouter } from 'next/navigation';
 import { StickerMuleLogo } from './svgs';
+import LoginMenu from './LoginMenu';
 
 export default function Header() {
   const router = useRouter();
@@ -20,6 +21,7 @@ export default function Header() {
         >
           <StickerMuleLogo />
         </button>
+        <LoginMenu />
       </div>
     </header>
   );
@@ -9,7 +9,7 @@ export default function Hero() {
             Stickers Visualizer
           </h1>
           <p className="text-base md:text-lg font-medium opacity-90">
-            See how your sticker looks on a MacBook Pro and drag it around to find the perfect placement
+            See how your sticker looks on a laptop, bottle or notebook and drag it around to find the perfect placement
           </p>
         </div>
       </div>
@@ -0,0 +1,131 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+
+import { UserDto, getMe, login, logout, signup } from '@/api/api';
+import { ApiError } from '@/api/base';
+
+// LoginMenu logs the visitor in or out. Sessions created while logged in are
+// owned by the user; anonymous sessions rely on their share tokens.
+export default function LoginMenu() {
+  const [user, setUser] = useState<UserDto | null>(null);
+  const [open, setOpen] = useState(false);
+  const [mode, setMode] = useState<'login' | 'signup'>('login');
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [displayName, setDisplayName] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    getMe()
+      .then(setUser)
+      .catch(() => setUser(null));
+  }, []);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+
+    setLoading(true);
+    try {
+      const loggedIn = mode === 'login'
+        ? await login(email.trim(), password)
+        : await signup(email.trim(), password, displayName.trim());
+      setUser(loggedIn);
+      setPassword('');
+      setOpen(false);
+    } catch (err) {
+      if (err instanceof ApiError && err.status === 429) {
+        setError('Too many failed attempts. Try again later.');
+      } else if (err instanceof ApiError && err.status === 401) {
+        setError('Wrong email or password');
+      } else if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError(`Unknown error: ${err}`);
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleLogout = async () => {
+    try {
+      await logout();
+      setUser(null);
+    } catch (err) {
+      console.error('Failed to log out:', err);
+    }
+  };
+
+  if (user) {
+    return (
+      <div className="flex items-center gap-3 text-sm text-white">
+        <span>{user.displayName || user.email}</span>
+        <button onClick={handleLogout} className="underline hover:opacity-80">
+          Log out
+        </button>
+      </div>
+    );
+  }
+
+  return (
+    <div className="relative">
+      <button onClick={() => setOpen(!open)} className="text-sm text-white underline hover:opacity-80">
+        Log in
+      </button>
+      {open && (
+        <form
+          onSubmit={handleSubmit}
+          className="absolute right-0 mt-2 w-72 p-4 space-y-3 bg-white rounded-lg shadow-lg border-2 border-sticker-gray z-50"
+        >
+          <input
+            type="email"
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+            placeholder="Email"
+            autoComplete="email"
+            className="w-full px-3 py-2 border-2 border-gray-200 rounded-lg text-sticker-text focus:outline-none focus:border-sticker-orange"
+            disabled={loading}
+          />
+          {mode === 'signup' && (
+            <input
+              type="text"
+              value={displayName}
+              onChange={(e) => setDisplayName(e.target.value)}
+              placeholder="Display name"
+              autoComplete="nickname"
+              className="w-full px-3 py-2 border-2 border-gray-200 rounded-lg text-sticker-text focus:outline-none focus:border-sticker-orange"
+              disabled={loading}
+            />
+          )}
+          <input
+            type="password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            placeholder="Password"
+            autoComplete={mode === 'login' ? 'current-password' : 'new-password'}
+            className="w-full px-3 py-2 border-2 border-gray-200 rounded-lg text-sticker-text focus:outline-none focus:border-sticker-orange"
+            disabled={loading}
+          />
+          {error && <p className="text-sm text-red-600 font-medium">{error}</p>}
+          <button
+            type="submit"
+            disabled={loading || !email.trim() || !password}
+            className="w-full px-4 py-2 bg-sticker-orange hover:bg-orange-600 text-white font-semibold rounded-lg transition-all duration-200 disabled:opacity-60 disabled:cursor-not-allowed"
+          >
+            {mode === 'login' ? 'Log in' : 'Sign up'}
+          </button>
+          <button
+            type="button"
+            onClick={() => setMode(mode === 'login' ? 'signup' : 'login')}
+            className="w-full text-sm text-gray-600 underline"
+          >
+            {mode === 'login' ? 'Create an account' : 'I already have an account'}
+          </button>
+        </form>
+      )}
+    </div>
+  );
+}
@@ -4,15 +4,8 @@ import { useState } from 'react';
 import { useRouter } from 'next/navigation';
 import Hero from '@/app/components/Hero';
 import { ErrorIcon, LoadingIcon } from '@/app/components/svgs';
-
-function generateRandomSessionId(): string {
-  const chars = 'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789';
-  let result = '';
-  for (let i = 0; i < 8; i++) {
-    result += chars.charAt(Math.floor(Math.random() * chars.length));
-  }
-  return result;
-}
+import { createSession } from '@/api/api';
+import { storeSessionTokens } from '@/shared/sessionTokens';
 
 export default function Home() {
   const [sessionId, setSessionId] = useState('');
@@ -44,9 +37,24 @@ export default function Home() {
     }
   };
 
-  const handleCreateNewSession = () => {
-    const newSessionId = generateRandomSessionId();
-    router.push(`/visualizer?sessionId=${newSessionId}`);
+  const handleCreateNewSession = async () => {
+    setError('');
+    setLoading(true);
+    try {
+      const session = await createSession();
+      // Only the view token goes in the URL; the edit token stays in this tab.
+      storeSessionTokens(session.id, session.tokens);
+      const token = session.tokens.viewToken ?? '';
+      router.push(`/visualizer?sessionId=${session.id}&token=${encodeURIComponent(token)}`);
+    } catch (err) {
+      if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError(`Unknown error: ${err}`);
+      }
+    } finally {
+      setLoading(false);
+    }
   };
 
   return (
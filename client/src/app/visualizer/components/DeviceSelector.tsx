@@ -0,0 +1,46 @@
+import { DeviceDto } from '@/api/api';
+
+interface Props {
+  devices: DeviceDto[];
+  deviceId: string;
+  variantId: string;
+  onChange: (deviceId: string, variantId: string) => void;
+}
+
+export default function DeviceSelector({ devices, deviceId, variantId, onChange }: Readonly<Props>) {
+  const device = devices.find(d => d.id === deviceId);
+
+  return (
+    <div className="mb-4 flex flex-col md:flex-row gap-4">
+      <label className="flex items-center gap-2 text-sm text-gray-600">
+        Device
+        <select
+          value={deviceId}
+          onChange={(e) => {
+            const next = devices.find(d => d.id === e.target.value);
+            onChange(e.target.value, next?.variants[0]?.id ?? '');
+          }}
+          className="px-3 py-2 border-2 border-gray-200 rounded-lg text-sticker-text focus:outline-none focus:border-sticker-orange"
+        >
+          {devices.map(d => (
+            <option key={d.id} value={d.id}>{d.name}</option>
+          ))}
+        </select>
+      </label>
+      {device && device.variants.length > 0 && (
+        <label className="flex items-center gap-2 text-sm text-gray-600">
+          Finish
+          <select
+            value={variantId}
+            onChange={(e) => onChange(deviceId, e.target.value)}
+            className="px-3 py-2 border-2 border-gray-200 rounded-lg text-sticker-text focus:outline-none focus:border-sticker-orange"
+          >
+            {device.variants.map(v => (
+              <option key={v.id} value={v.id}>{v.name}</option>
+            ))}
+          </select>
+        </label>
+      )}
+    </div>
+  );
+}
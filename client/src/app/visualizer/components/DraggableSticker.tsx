@@ -12,6 +12,8 @@ interface Props {
   };
   containerWidth: number;
   containerHeight: number;
+  lidWidth: number;
+  lidHeight: number;
   zIndex?: number;
   initialPosition: Position;
   onPositionChange?: (id: string, x: number, y: number) => void;
@@ -23,6 +25,8 @@ export default function DraggableSticker({
   stickerSize,
   containerWidth,
   containerHeight,
+  lidWidth,
+  lidHeight,
   zIndex = 10,
   initialPosition,
   onPositionChange
@@ -30,14 +34,10 @@ export default function DraggableSticker({
   const [position, setPosition] = useState({ ...initialPosition });
   const nodeRef = useRef(null);
 
-  // Calculate sticker display size based on real-world dimensions
-  // Assuming MacBook Pro 14" lid usable area is approximately 12.3" x 8.5"
-  // Convert sticker size from inches to pixels with appropriate scaling
-  const MACBOOK_LID_WIDTH_INCHES = 12.3;
-  const MACBOOK_LID_HEIGHT_INCHES = 8.5;
-  
-  const scaleX = containerWidth / MACBOOK_LID_WIDTH_INCHES;
-  const scaleY = containerHeight / MACBOOK_LID_HEIGHT_INCHES;
+  // Convert the sticker size from inches to pixels at the scale the lid of
+  // the selected device is drawn at.
+  const scaleX = containerWidth / lidWidth;
+  const scaleY = containerHeight / lidHeight;
   const scale = Math.min(scaleX, scaleY); // Use smaller scale to maintain aspect ratio
   
   const stickerDisplayWidth = stickerSize.width * scale;
@@ -29,7 +29,7 @@ export default function Info() {
             - https://www.stickermule.com/herman/item/14591453 <br />
             - https://www.stickermule.com/zamaxdesign/item/12572771?origin=PUBLIC_PROFILE <br />
             <br />
-            <span>Visualize stickers at actual size on MacBook Pro lid. Drag to arrange multiple stickers.  </span>
+            <span>Visualize stickers at actual size on the device you pick. Drag to arrange multiple stickers.  </span>
           </p>
         </div>
       </div>
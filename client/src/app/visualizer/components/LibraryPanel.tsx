@@ -0,0 +1,118 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import Image from 'next/image';
+
+import { LibraryItemDto, listLibrary, saveLibraryItem } from '@/api/api';
+import { ApiError } from '@/api/base';
+import { ErrorIcon } from '@/app/components/svgs';
+import { StickerWithId } from '@/models/StickerWithId';
+
+interface Props {
+  stickers: StickerWithId[];
+  onAddSticker: (sticker: StickerWithId) => void;
+}
+
+// LibraryPanel lists the stickers saved to the logged-in user's library, adds
+// them to the laptop and saves stickers from the laptop to the library. It is
+// hidden for visitors who are not logged in.
+export default function LibraryPanel({ stickers, onAddSticker }: Readonly<Props>) {
+  const [items, setItems] = useState<LibraryItemDto[] | null>(null);
+  const [saving, setSaving] = useState('');
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    listLibrary()
+      .then(setItems)
+      .catch((err) => {
+        if (!(err instanceof ApiError && err.status === 401)) {
+          setError('Failed to load your library');
+        }
+      });
+  }, []);
+
+  if (items === null && !error) {
+    return null;
+  }
+
+  const savedUrls = new Set((items ?? []).map(item => item.url));
+
+  const handleAdd = (item: LibraryItemDto) => {
+    onAddSticker({
+      id: crypto.randomUUID(),
+      title: item.title,
+      productImage: item.url,
+      size: item.size
+    });
+  };
+
+  const handleSave = async (sticker: StickerWithId, index: number) => {
+    setError('');
+    setSaving(sticker.id);
+    try {
+      const item = await saveLibraryItem({
+        title: sticker.title || `Sticker ${index + 1}`,
+        url: sticker.productImage,
+        size: sticker.size
+      });
+      setItems(prev => [item, ...(prev ?? [])]);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to save sticker');
+    } finally {
+      setSaving('');
+    }
+  };
+
+  const unsaved = stickers.filter(sticker => !savedUrls.has(sticker.productImage));
+
+  return (
+    <div className="mb-8 p-6 bg-white rounded-xl border-2 border-sticker-gray shadow-sm">
+      <h3 className="text-xl font-bold text-sticker-brown mb-4">
+        My Library
+      </h3>
+
+      {items && items.length === 0 && (
+        <p className="text-sm text-gray-600 mb-4">Save stickers from your laptop to reuse them in other sessions.</p>
+      )}
+
+      {items && items.length > 0 && (
+        <div className="flex flex-wrap gap-3 mb-4">
+          {items.map(item => (
+            <button
+              key={item.id}
+              onClick={() => handleAdd(item)}
+              className="flex flex-col items-center w-24 p-2 border-2 border-gray-200 rounded-lg hover:border-sticker-orange transition-colors duration-200"
+              title={`Add ${item.title || 'this sticker'} to the laptop`}
+            >
+              <Image src={item.url} alt={item.title} width={64} height={64} className="object-contain h-16" unoptimized />
+              <span className="mt-1 text-xs text-sticker-text truncate w-full">{item.title}</span>
+            </button>
+          ))}
+        </div>
+      )}
+
+      {unsaved.length > 0 && (
+        <div className="flex flex-wrap gap-2">
+          {unsaved.map(sticker => (
+            <button
+              key={sticker.id}
+              onClick={() => handleSave(sticker, stickers.indexOf(sticker))}
+              disabled={saving !== ''}
+              className="px-3 py-1 text-sm bg-gray-200 hover:bg-gray-300 text-gray-700 rounded transition-colors duration-200 disabled:opacity-60"
+            >
+              {saving === sticker.id ? 'Saving...' : `Save sticker ${stickers.indexOf(sticker) + 1} to library`}
+            </button>
+          ))}
+        </div>
+      )}
+
+      {error && (
+        <div className="mt-4 p-3 bg-red-50 border border-red-200 rounded-lg">
+          <p className="text-sm text-red-600 font-medium flex items-center">
+            <ErrorIcon /> {error}
+          </p>
+        </div>
+      )}
+    </div>
+  );
+}
@@ -31,14 +31,14 @@ export default function StickerManagement({
             <div className="flex items-center space-x-3">
               <Image 
                 src={sticker.productImage} 
-                alt={`Sticker ${stickers.length - index}`}
+                alt={sticker.title || `Sticker ${stickers.length - index}`}
                 width={32}
                 height={32}
                 className="w-8 h-8 object-cover rounded"
               />
               <div className="text-sm text-gray-500">
                 <div className="font-medium">
-                  Sticker #{sticker.id.slice(0, 8)}
+                  {sticker.title || `Sticker #${sticker.id.slice(0, 8)}`}
                   <span className="text-xs text-gray-400 ml-1">
                     (Layer {index === 0 ? 'Top' : index === stickers.length - 1 ? 'Bottom' : stickers.length - index})
                   </span>
@@ -2,57 +2,84 @@ import { useState, useRef, useEffect } from 'react';
 import Image from 'next/image';
 
 import { StickerWithId } from '@/models/StickerWithId';
-import { SaveSessionDataRequest, SaveStickerData, saveSession } from '@/api/api';
+import { DeviceDto, SaveSessionDataRequest, SaveStickerData, listDevices, saveSession } from '@/api/api';
+import { DEFAULT_DEVICE_ID } from '@/shared/const';
+import { StoredSessionTokens, shareUrl, storeSessionTokens } from '@/shared/sessionTokens';
 
 import DraggableSticker from './DraggableSticker';
 import StickerManagement from './StickerManagement';
 import Info from './Info';
 import InstructionsOverlay from './InstructionsOverlay';
 import AddSticker from './AddSticker';
+import LibraryPanel from './LibraryPanel';
+import DeviceSelector from './DeviceSelector';
+import UnlockSession from './UnlockSession';
 import { Position, getSession } from '@/api/api';
+import { ApiError } from '@/api/base';
 
 interface Props {
   sessionId: string;
+  tokens: StoredSessionTokens;
   setError: (error: string) => void;
   setLoading: (loading: boolean) => void;
 }
 
-export default function StickerVisualizer({ sessionId, setError, setLoading }: Readonly<Props>) {
+export default function StickerVisualizer({ sessionId, tokens, setError, setLoading }: Readonly<Props>) {
+  // The edit token also grants view access.
+  const token = tokens.editToken || tokens.viewToken || '';
+  const [unlockToken, setUnlockToken] = useState(tokens.unlockToken ?? '');
+  // Set while the session has a passcode and unlockToken does not open it.
+  const [locked, setLocked] = useState(false);
   // TODO: Consolidate stickers and stickerPositions.
   const [stickers, setStickers] = useState<StickerWithId[]>([]);
   const [stickerPositions, setStickerPositions] = useState<Record<string, Position>>({});
   const containerRef = useRef<HTMLDivElement>(null);
+  // Session version from the last load or save, sent back as If-Match.
+  const versionRef = useRef(0);
   const [containerDimensions, setContainerDimensions] = useState({ width: 0, height: 0 });
   const [sessionUrl, setSessionUrl] = useState<string>('');
-  const [copySuccess, setCopySuccess] = useState(false);
+  const [copied, setCopied] = useState<'view' | 'edit' | null>(null);
+  // The device catalog and the session's device, as served by /devices.
+  const [devices, setDevices] = useState<DeviceDto[]>([]);
+  const [deviceId, setDeviceId] = useState(DEFAULT_DEVICE_ID);
+  const [variantId, setVariantId] = useState('');
 
-  const MACBOOK_SPECS = {
-    lidWidth: 12.3, // inches
-    lidHeight: 8.5,  // inches
-    displayName: '14" MacBook Pro'
-  };
+  const device = devices.find(d => d.id === deviceId);
+  const variant = device?.variants.find(v => v.id === variantId) ?? device?.variants[0];
 
-  // Update container dimensions when component mounts or window resizes
+  // Track the container size; it changes with the window and with the aspect
+  // ratio of the selected device.
   useEffect(() => {
-    const updateDimensions = () => {
-      if (containerRef.current) {
-        const rect = containerRef.current.getBoundingClientRect();
-        setContainerDimensions({
-          width: rect.width,
-          height: rect.height
-        });
-      }
-    };
+    const container = containerRef.current;
+    if (!container) {
+      return;
+    }
+    const observer = new ResizeObserver(([entry]) => {
+      setContainerDimensions({
+        width: entry.contentRect.width,
+        height: entry.contentRect.height
+      });
+    });
+    observer.observe(container);
+    return () => observer.disconnect();
+  }, []);
 
+  useEffect(() => {
     const fetchData = async () => {
       try {
         setLoading(true);
-        const data = await getSession(sessionId);
+        const [data, catalog] = await Promise.all([getSession(sessionId, token, unlockToken), listDevices()]);
+        setLocked(false);
+        versionRef.current = data.session.version;
+        setDevices(catalog);
+        setDeviceId(data.deviceId || DEFAULT_DEVICE_ID);
+        setVariantId(data.deviceVariant ?? '');
         const newStickers: StickerWithId[] = [];
         const newStickerPositions: Record<string, Position> = {};
         (data.stickers || []).forEach((sticker: SaveStickerData) => {
           newStickers.push({
             id: sticker.stickerId,
+            title: sticker.title,
             productImage: sticker.url,
             size: sticker.size,
           });
@@ -62,7 +89,9 @@ export default function StickerVisualizer({ sessionId, setError, setLoading }: R
         setStickers(newStickers);
         setStickerPositions(newStickerPositions)
       } catch (err) {
-        if (err instanceof Error) {
+        if (err instanceof ApiError && err.status === 423) {
+          setLocked(true);
+        } else if (err instanceof Error) {
           setError(err.message);
         } else {
           setError('Unknown error occurred');
@@ -72,23 +101,27 @@ export default function StickerVisualizer({ sessionId, setError, setLoading }: R
       }
     };
 
-    updateDimensions();
     fetchData();
-    window.addEventListener('resize', updateDimensions);
-    return () => window.removeEventListener('resize', updateDimensions);
-  }, [sessionId, setError, setLoading]);
+  }, [sessionId, token, unlockToken, setError, setLoading]);
 
-  // Set session URL when component mounts
+  // The link shown and copied by default only grants view access.
   useEffect(() => {
-    if (typeof window !== 'undefined') {
-      setSessionUrl(window.location.href);
-    }
-  }, []);
+    setSessionUrl(shareUrl(sessionId, tokens));
+  }, [sessionId, tokens]);
+
+  // Pixels per inch of the lid at the current container size. Positions are
+  // kept in inches so sessions load in place on any screen size.
+  const lidWidth = device?.lidWidth ?? 1;
+  const lidHeight = device?.lidHeight ?? 1;
+  const pixelsPerInch = Math.min(
+    containerDimensions.width / lidWidth,
+    containerDimensions.height / lidHeight
+  );
 
   const handlePositionChange = (stickerId: string, x: number, y: number) => {
     setStickerPositions(prev => ({
       ...prev,
-      [stickerId]: { x, y }
+      [stickerId]: { x: x / pixelsPerInch, y: y / pixelsPerInch }
     }));
   };
 
@@ -135,131 +168,190 @@ export default function StickerVisualizer({ sessionId, setError, setLoading }: R
     setStickerPositions(newStickerPositions)
   };
 
-  const handleCopyUrl = async () => {
+  const handleCopyUrl = async (kind: 'view' | 'edit') => {
     try {
-      await navigator.clipboard.writeText(sessionUrl);
-      setCopySuccess(true);
-      setTimeout(() => setCopySuccess(false), 2000);
+      await navigator.clipboard.writeText(kind === 'edit' ? shareUrl(sessionId, tokens, true) : sessionUrl);
+      setCopied(kind);
+      setTimeout(() => setCopied(null), 2000);
     } catch (err) {
       console.error('Failed to copy URL:', err);
     }
   };
 
+  const handleUnlock = (nextUnlockToken: string) => {
+    storeSessionTokens(sessionId, { ...tokens, unlockToken: nextUnlockToken });
+    setUnlockToken(nextUnlockToken);
+  };
+
   const handleSaveSession = () => {
     const sessionData: SaveSessionDataRequest = {
       sessionId: sessionId,
-      stickers: stickers.map(sticker => {
+      stickers: stickers.map((sticker, index) => {
         return {
           stickerId: sticker.id,
+          title: sticker.title,
           url: sticker.productImage,
           size: sticker.size,
-          position: stickerPositions[sticker.id] || { x: 0, y: 0 }
+          position: stickerPositions[sticker.id] || { x: 0, y: 0 },
+          zIndex: index
         }
-      })
+      }),
+      deviceId,
+      deviceVariant: variant?.id
     };
 
-    saveSession(sessionData)
+    saveSession(sessionData, versionRef.current, token, unlockToken)
+      .then((version) => { versionRef.current = version; })
+      .catch((err) => {
+        if (err instanceof ApiError && err.status === 412) {
+          setError('This session was changed elsewhere. Reload to see the latest version.');
+        } else if (err instanceof ApiError && err.status === 423) {
+          setLocked(true);
+        } else {
+          console.error('Failed to save session:', err);
+        }
+      });
   };
   
   return (
     <div className="w-full max-w-6xl mx-auto">
 
-      <Info />
-
-      {/* Session Info and Save Button */}
-      <div className="mb-4 flex justify-between items-center">
-        <div className="flex items-center gap-4 text-sm">
-          <span className="text-gray-600">
-            Session ID: <span className="font-mono text-gray-800">{sessionId}</span>
-          </span>
-          <div className="flex items-center gap-2">
-            <span className="text-gray-600">URL:</span>
-            <code className="text-xs bg-gray-100 px-2 py-1 rounded max-w-xs truncate">
-              {sessionUrl || 'Loading...'}
-            </code>
-            <button
-              onClick={handleCopyUrl}
-              className="px-3 py-1 text-sm bg-blue-500 hover:bg-blue-600 text-white rounded transition-colors duration-200"
-              title="Copy URL to clipboard"
-            >
-              {copySuccess ? 'Copied!' : 'Copy'}
-            </button>
+      {locked && <UnlockSession sessionId={sessionId} token={token} onUnlock={handleUnlock} />}
+
+      {/* Kept mounted while locked so the container keeps being observed. */}
+      <div className={locked ? 'hidden' : ''}>
+        <Info />
+
+        {/* Session Info and Save Button */}
+        <div className="mb-4 flex justify-between items-center">
+          <div className="flex items-center gap-4 text-sm">
+            <span className="text-gray-600">
+              Session ID: <span className="font-mono text-gray-800">{sessionId}</span>
+            </span>
+            <div className="flex items-center gap-2">
+              <span className="text-gray-600">URL:</span>
+              <code className="text-xs bg-gray-100 px-2 py-1 rounded max-w-xs truncate">
+                {sessionUrl || 'Loading...'}
+              </code>
+              <button
+                onClick={() => handleCopyUrl('view')}
+                className="px-3 py-1 text-sm bg-blue-500 hover:bg-blue-600 text-white rounded transition-colors duration-200"
+                title="Copy a view-only link to clipboard"
+              >
+                {copied === 'view' ? 'Copied!' : 'Copy'}
+              </button>
+              {tokens.editToken && (
+                <button
+                  onClick={() => handleCopyUrl('edit')}
+                  className="px-3 py-1 text-sm bg-red-500 hover:bg-red-600 text-white rounded transition-colors duration-200"
+                  title="Copy a link that lets anyone who has it edit this session"
+                >
+                  {copied === 'edit' ? 'Copied!' : 'Copy edit link'}
+                </button>
+              )}
+            </div>
           </div>
+          <button
+            onClick={handleSaveSession}
+            className="px-6 py-2 bg-gray-300 hover:bg-gray-400 text-gray-700 font-semibold rounded-lg transition-all duration-200 shadow-md hover:shadow-lg focus:outline-none focus:ring-2 focus:ring-gray-400 disabled:opacity-60 disabled:cursor-not-allowed disabled:hover:bg-gray-300"
+            disabled={stickers.length === 0}
+          >
+            Save Session
+          </button>
         </div>
-        <button
-          onClick={handleSaveSession}
-          className="px-6 py-2 bg-gray-300 hover:bg-gray-400 text-gray-700 font-semibold rounded-lg transition-all duration-200 shadow-md hover:shadow-lg focus:outline-none focus:ring-2 focus:ring-gray-400 disabled:opacity-60 disabled:cursor-not-allowed disabled:hover:bg-gray-300"
-          disabled={stickers.length === 0}
-        >
-          Save Session
-        </button>
-      </div>
 
-      <AddSticker onAddSticker={handleAddSticker} />
+        <AddSticker onAddSticker={handleAddSticker} />
 
-      <div className="relative bg-gradient-to-b from-gray-100 to-gray-200 rounded-2xl p-8 shadow-2xl">
-        <div className="relative mx-auto" style={{ maxWidth: '900px' }}>
-          <div 
-            ref={containerRef}
-            className="relative w-full aspect-[4/3] bg-gray-800 rounded-lg shadow-lg overflow-hidden"
-          >
-            <Image
-              src="/mbp14-black.jpeg"
-              alt="MacBook Pro"
-              fill
-              className="object-cover"
-              unoptimized
-              priority
-            />
-            <div className="absolute inset-0 bg-opacity-100 rounded-lg" />
-            
-            {/* Draggable Stickers */}
-            {containerDimensions.width > 0 && containerDimensions.height > 0 && 
-              stickers.map((sticker, index) => (
-                <DraggableSticker
-                  key={sticker.id}
-                  id={sticker.id}
-                  stickerImage={sticker.productImage}
-                  stickerSize={sticker.size}
-                  containerWidth={containerDimensions.width}
-                  containerHeight={containerDimensions.height}
-                  zIndex={10 + index} // Higher index = higher z-index (on top)
-                  initialPosition={stickerPositions[sticker.id]}
-                  onPositionChange={handlePositionChange}
+        <LibraryPanel stickers={stickers} onAddSticker={handleAddSticker} />
+
+        {devices.length > 0 && (
+          <DeviceSelector
+            devices={devices}
+            deviceId={deviceId}
+            variantId={variant?.id ?? ''}
+            onChange={(nextDeviceId, nextVariantId) => {
+              setDeviceId(nextDeviceId);
+              setVariantId(nextVariantId);
+            }}
+          />
+        )}
+
+        <div className="relative bg-gradient-to-b from-gray-100 to-gray-200 rounded-2xl p-8 shadow-2xl">
+          <div className="relative mx-auto" style={{ maxWidth: '900px' }}>
+            <div 
+              ref={containerRef}
+              className="relative w-full rounded-lg shadow-lg overflow-hidden"
+              style={{
+                aspectRatio: `${lidWidth} / ${lidHeight}`,
+                backgroundColor: variant?.color ?? '#1f2937'
+              }}
+            >
+              {variant?.backgroundImage && (
+                <Image
+                  src={variant.backgroundImage}
+                  alt={`${device?.name} in ${variant.name}`}
+                  fill
+                  className="object-cover"
+                  unoptimized
+                  priority
                 />
-              ))
-            }
+              )}
+              <div className="absolute inset-0 bg-opacity-100 rounded-lg" />
+            
+              {/* Draggable Stickers */}
+              {device && containerDimensions.width > 0 && containerDimensions.height > 0 &&
+                stickers.map((sticker, index) => (
+                  <DraggableSticker
+                    // Remount on device changes so positions are redrawn at the new scale.
+                    key={`${device.id}-${sticker.id}`}
+                    id={sticker.id}
+                    stickerImage={sticker.productImage}
+                    stickerSize={sticker.size}
+                    containerWidth={containerDimensions.width}
+                    containerHeight={containerDimensions.height}
+                    lidWidth={lidWidth}
+                    lidHeight={lidHeight}
+                    zIndex={10 + index} // Higher index = higher z-index (on top)
+                    initialPosition={{
+                      x: (stickerPositions[sticker.id]?.x ?? 0) * pixelsPerInch,
+                      y: (stickerPositions[sticker.id]?.y ?? 0) * pixelsPerInch
+                    }}
+                    onPositionChange={handlePositionChange}
+                  />
+                ))
+              }
 
-          </div>
+            </div>
+
+            <InstructionsOverlay />
 
-          <InstructionsOverlay />
-
-          {/* Model Info */}
-          <div className="mt-4 text-center">
-            <h3 className="text-lg font-bold text-sticker-brown mb-2">
-              {MACBOOK_SPECS.displayName}
-            </h3>
-            <div className="flex justify-center space-x-6 text-sm text-sticker-text">
-              <span>Lid Area: {MACBOOK_SPECS.lidWidth}&quot; × {MACBOOK_SPECS.lidHeight}&quot;</span>
-              <span>•</span>
-              <span>
-                Total Coverage: {
-                  (stickers.reduce((total, sticker) => 
-                    total + (sticker.size.width * sticker.size.height), 0
-                  ) / (MACBOOK_SPECS.lidWidth * MACBOOK_SPECS.lidHeight) * 100).toFixed(1)
-                }%
-              </span>
+            {/* Model Info */}
+            <div className="mt-4 text-center">
+              <h3 className="text-lg font-bold text-sticker-brown mb-2">
+                {device?.name ?? 'Loading device...'}
+              </h3>
+              <div className="flex justify-center space-x-6 text-sm text-sticker-text">
+                <span>Lid Area: {lidWidth}&quot; × {lidHeight}&quot;</span>
+                <span>•</span>
+                <span>
+                  Total Coverage: {
+                    (stickers.reduce((total, sticker) => 
+                      total + (sticker.size.width * sticker.size.height), 0
+                    ) / (lidWidth * lidHeight) * 100).toFixed(1)
+                  }%
+                </span>
+              </div>
             </div>
           </div>
         </div>
-      </div>
 
-      <StickerManagement
-        stickers={stickers}
-        onMoveUp={handleMoveUp}
-        onMoveDown={handleMoveDown}
-        onRemoveSticker={handleRemoveSticker}
-      />
+        <StickerManagement
+          stickers={stickers}
+          onMoveUp={handleMoveUp}
+          onMoveDown={handleMoveDown}
+          onRemoveSticker={handleRemoveSticker}
+        />
+      </div>
 
 
     </div>
@@ -0,0 +1,86 @@
+'use client';
+
+import { useState } from 'react';
+
+import { unlockSession } from '@/api/api';
+import { ApiError } from '@/api/base';
+import { LoadingIcon, ErrorIcon } from '@/app/components/svgs';
+
+interface Props {
+  sessionId: string;
+  token: string;
+  onUnlock: (unlockToken: string) => void;
+}
+
+export default function UnlockSession({ sessionId, token, onUnlock }: Readonly<Props>) {
+  const [passcode, setPasscode] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+
+  const handleUnlock = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+
+    setLoading(true);
+    try {
+      const unlock = await unlockSession(sessionId, passcode, token);
+      setPasscode('');
+      onUnlock(unlock.unlockToken);
+    } catch (err) {
+      if (err instanceof ApiError && err.status === 401) {
+        setError('Wrong passcode');
+      } else if (err instanceof ApiError && err.status === 429) {
+        setError('Too many failed attempts. Try again later.');
+      } else if (err instanceof Error) {
+        setError(err.message);
+      } else {
+        setError('Failed to unlock session');
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="mb-8 p-6 bg-white rounded-xl border-2 border-sticker-gray shadow-sm">
+      <h3 className="text-xl font-bold text-sticker-brown mb-4">
+        This Session Is Locked
+      </h3>
+      <form onSubmit={handleUnlock} className="space-y-4">
+        <div className="flex flex-col md:flex-row gap-4">
+          <div className="flex-1">
+            <input
+              type="password"
+              value={passcode}
+              onChange={(e) => setPasscode(e.target.value)}
+              placeholder="Enter the session passcode"
+              autoComplete="off"
+              className="w-full px-4 py-3 border-2 border-gray-200 rounded-lg text-sticker-text placeholder-gray-400 focus:outline-none focus:border-sticker-orange focus:ring-0 transition-colors duration-200"
+              disabled={loading}
+            />
+          </div>
+          <button
+            type="submit"
+            disabled={loading || !passcode}
+            className="px-6 py-3 bg-sticker-orange hover:bg-orange-600 text-white font-semibold rounded-lg transition-all duration-200 disabled:opacity-60 disabled:cursor-not-allowed disabled:hover:bg-sticker-orange"
+          >
+            {loading ? (
+              <span className="flex items-center">
+                <LoadingIcon /> Unlocking...
+              </span>
+            ) : (
+              'Unlock'
+            )}
+          </button>
+        </div>
+        {error && (
+          <div className="p-3 bg-red-50 border border-red-200 rounded-lg">
+            <p className="text-sm text-red-600 font-medium flex items-center">
+              <ErrorIcon /> {error}
+            </p>
+          </div>
+        )}
+      </form>
+    </div>
+  );
+}
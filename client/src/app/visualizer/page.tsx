@@ -4,14 +4,25 @@ import { useEffect, useState, Suspense } from 'react';
 import { useSearchParams, useRouter } from 'next/navigation';
 import Hero from '@/app/components/Hero';
 import StickerVisualizer from './components/StickerVisualizer';
+import { StoredSessionTokens, sessionTokensFromLocation } from '@/shared/sessionTokens';
 
 function VisualizerContent() {
   const searchParams = useSearchParams();
   const router = useRouter();
   const sessionId = searchParams.get('sessionId');
+  // Share links carry the view token of protected sessions; edit tokens come
+  // from the #edit= fragment or this tab's storage.
+  const viewToken = searchParams.get('token') ?? '';
 
   const [error, setError] = useState<string>('');
   const [loading, setLoading] = useState(true);
+  const [tokens, setTokens] = useState<StoredSessionTokens | null>(null);
+
+  useEffect(() => {
+    if (sessionId) {
+      setTokens(sessionTokensFromLocation(sessionId, viewToken));
+    }
+  }, [sessionId, viewToken]);
 
   useEffect(() => {
     if (!sessionId) {
@@ -49,11 +60,12 @@ function VisualizerContent() {
         </div>
       )}
 
-      { sessionId && (
+      { sessionId && tokens && (
         <div className="container mx-auto px-4 py-16">
           <div className="max-w-6xl mx-auto">
             <StickerVisualizer 
               sessionId={sessionId}
+              tokens={tokens}
               setError={setError}
               setLoading={setLoading}
             />
@@ -74,7 +86,7 @@ function LoadingFallback() {
               Stickers Visualizer
             </h1>
             <p className="text-base md:text-lg font-medium opacity-90">
-              See how your sticker looks on a MacBook Pro and drag it around to find the perfect placement
+              See how your sticker looks on a laptop, bottle or notebook and drag it around to find the perfect placement
             </p>
           </div>
         </div>
@@ -1,4 +1,6 @@
 export const BASE_API = 'https://mule-be-1027839195257.us-east4.run.app';
+// Sessions that never picked a device were made on the 14" MacBook Pro.
+export const DEFAULT_DEVICE_ID = 'macbook-pro-14';
 export const STICKER_MULE_LOGO =
   'https://press.stickermule.com/image/https%3A%2F%2Fprod-files-secure.s3.us-west-2.amazonaws.com%2F82aad7a9-0984-4f35-be2c-bc71b9f47fdc%2F7920e4d8-ed39-414c-800a-49dcecbdd35c%2FBrown_Logo_on_white.png?table=block&id=b0524f9e-6b4a-4cd7-baf0-ab9a53651a28&spaceId=82aad7a9-0984-4f35-be2c-bc71b9f47fdc&width=2000&userId=&cache=v2';
 export const STICKER_MULE_LOGO_SMALL =
@@ -0,0 +1,58 @@
+import { SessionTokensDto } from '@/api/api';
+
+// StoredSessionTokens adds the unlock token of a session with a passcode.
+export interface StoredSessionTokens extends SessionTokensDto {
+  unlockToken?: string
+}
+
+// Tokens are kept per tab in sessionStorage so the edit token never has to
+// sit in the address bar, where it would leak into share links and history.
+function storageKey(sessionId: string): string {
+  return `session-tokens:${sessionId}`;
+}
+
+export function storeSessionTokens(sessionId: string, tokens: StoredSessionTokens) {
+  sessionStorage.setItem(storageKey(sessionId), JSON.stringify(tokens));
+}
+
+function loadSessionTokens(sessionId: string): StoredSessionTokens {
+  try {
+    return JSON.parse(sessionStorage.getItem(storageKey(sessionId)) ?? '{}');
+  } catch {
+    return {};
+  }
+}
+
+// sessionTokensFromLocation combines the view token of a share link, an edit
+// token passed in the #edit= fragment and the tokens stored for the session.
+// The fragment is removed from the address bar once it has been stored.
+export function sessionTokensFromLocation(sessionId: string, viewToken: string): StoredSessionTokens {
+  const stored = loadSessionTokens(sessionId);
+  const editToken = new URLSearchParams(window.location.hash.slice(1)).get('edit');
+  if (editToken) {
+    window.history.replaceState(null, '', window.location.pathname + window.location.search);
+  }
+
+  const tokens: StoredSessionTokens = {
+    viewToken: viewToken || stored.viewToken,
+    editToken: editToken || stored.editToken,
+    unlockToken: stored.unlockToken
+  };
+  storeSessionTokens(sessionId, tokens);
+  return tokens;
+}
+
+// shareUrl builds a link to the session. The default link carries only the
+// view token; an edit link puts the edit token in the fragment, which browsers
+// do not send to servers.
+export function shareUrl(sessionId: string, tokens: SessionTokensDto, edit = false): string {
+  const url = new URL('/visualizer', window.location.origin);
+  url.searchParams.set('sessionId', sessionId);
+  if (tokens.viewToken) {
+    url.searchParams.set('token', tokens.viewToken);
+  }
+  if (edit && tokens.editToken) {
+    url.hash = new URLSearchParams({ edit: tokens.editToken }).toString();
+  }
+  return url.toString();
+}
@@ -39,15 +39,87 @@ func main() {
 		}
 	}
 
-	stickerService := services.NewStickerService()
+	imageService := services.NewImageService(dbClient)
+	imageHandler := handlers.NewImageHandler(imageService)
+
+	productService := services.NewProductService(dbClient)
+	productHandler := handlers.NewProductHandler(productService)
+
+	stickerService := services.NewStickerService(imageService, productService)
 	stickerHandler := handlers.NewStickerHandler(stickerService)
 
-	sessionService := services.NewSessionService(dbClient)
-	sessionHandler := handlers.NewSessionHandler(sessionService)
+	deviceService := services.NewDeviceService(dbClient)
+	deviceHandler := handlers.NewDeviceHandler(deviceService)
+
+	userService := services.NewUserService(dbClient, services.NewLoginConfig())
+	userHandler := handlers.NewUserHandler(userService)
+
+	workspaceService := services.NewWorkspaceService(dbClient)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService, userService)
+
+	libraryService := services.NewLibraryService(dbClient)
+	libraryHandler := handlers.NewLibraryHandler(libraryService, userService)
+
+	retentionConfig := services.NewRetentionConfig()
+	sessionService := services.NewSessionService(dbClient, deviceService, services.NewSessionIDConfig(), services.NewRevisionConfig(), services.NewUnlockConfig(), retentionConfig)
+	sessionHandler := handlers.NewSessionHandler(sessionService, userService)
+
+	// Purge deleted, expired and abandoned sessions in the background.
+	// Its counters are served at /metrics/janitor when METRICS_TOKEN is set.
+	var janitor *services.SessionJanitor
+	if dbClient != nil {
+		janitor = services.NewSessionJanitor(dbClient, retentionConfig)
+		go janitor.Run(ctx)
+	}
+	janitorHandler := handlers.NewJanitorHandler(janitor)
 
 	http.HandleFunc("/process-sticker-url", middleware.CORS(stickerHandler.ProcessStickerURL))
+	http.HandleFunc("/process-image-url", middleware.CORS(stickerHandler.ProcessExternalImageURL))
+	http.HandleFunc("/upload-image", middleware.CORS(stickerHandler.UploadImage))
+	http.HandleFunc("/signup", middleware.CORS(userHandler.Signup))
+	http.HandleFunc("/login", middleware.CORS(userHandler.Login))
+	http.HandleFunc("/logout", middleware.CORS(userHandler.Logout))
+	http.HandleFunc("/me", middleware.CORS(userHandler.Me))
+	http.HandleFunc("/workspaces", middleware.CORS(workspaceHandler.Workspaces))
+	http.HandleFunc("/workspaces/{id}", middleware.CORS(workspaceHandler.Workspace))
+	http.HandleFunc("/workspaces/{id}/members", middleware.CORS(workspaceHandler.WorkspaceMembers))
+	http.HandleFunc("/workspaces/{id}/members/{userId}", middleware.CORS(workspaceHandler.WorkspaceMember))
+	http.HandleFunc("/library", middleware.CORS(libraryHandler.Library))
+	http.HandleFunc("/library/{itemId}", middleware.CORS(libraryHandler.LibraryItem))
+	http.HandleFunc("/sessions", middleware.CORS(sessionHandler.Sessions))
+	// Session routes check the caller's access to the session in {id} before
+	// the handler runs; restore, claim and unlock do their own checks.
+	http.HandleFunc("/sessions/{id}", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.Session)))
+	http.HandleFunc("/sessions/{id}/restore", middleware.CORS(sessionHandler.RestoreSession))
+	http.HandleFunc("/sessions/{id}/expiry", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionExpiry)))
+	http.HandleFunc("/sessions/{id}/tags", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionTags)))
+	http.HandleFunc("/sessions/{id}/workspace", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionWorkspace)))
+	http.HandleFunc("/sessions/{id}/stickers", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionStickers)))
+	http.HandleFunc("/sessions/{id}/stickers/{stickerId}", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionSticker)))
+	http.HandleFunc("/sessions/{id}/library-stickers", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionLibraryStickers)))
+	http.HandleFunc("/sessions/{id}/revisions", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.ListRevisions)))
+	http.HandleFunc("/sessions/{id}/revisions/{version}", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.GetRevision)))
+	http.HandleFunc("/sessions/{id}/revisions/{version}/restore", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.RestoreRevision)))
+	http.HandleFunc("/sessions/{id}/events", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.ListEvents)))
+	http.HandleFunc("/sessions/{id}/at", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.GetSessionAt)))
+	http.HandleFunc("/sessions/{id}/fork", middleware.CORS(sessionHandler.RequireViewAccess(sessionHandler.ForkSession)))
+	http.HandleFunc("/sessions/{id}/lineage", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.GetLineage)))
+	http.HandleFunc("/sessions/{id}/claim", middleware.CORS(sessionHandler.ClaimSession))
+	http.HandleFunc("/sessions/{id}/tokens/{kind}", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionToken)))
+	http.HandleFunc("/sessions/{id}/passcode", middleware.CORS(sessionHandler.RequireAccess(sessionHandler.SessionPasscode)))
+	http.HandleFunc("/sessions/{id}/unlock", middleware.CORS(sessionHandler.UnlockSession))
 	http.HandleFunc("/save-session", middleware.CORS(sessionHandler.SaveSession))
 	http.HandleFunc("/get-session", middleware.CORS(sessionHandler.GetSession))
+	http.HandleFunc("/v2/save-session", middleware.CORS(sessionHandler.SaveSessionV2))
+	http.HandleFunc("/v2/get-session", middleware.CORS(sessionHandler.GetSessionV2))
+	http.HandleFunc("/get-session-coverage", middleware.CORS(sessionHandler.GetSessionCoverage))
+	http.HandleFunc("/get-session-palette", middleware.CORS(sessionHandler.GetSessionPalette))
+	http.HandleFunc("/devices", middleware.CORS(deviceHandler.ListDevices))
+	http.HandleFunc("/devices/{id}", middleware.CORS(deviceHandler.GetDevice))
+	http.HandleFunc("/images/{hash}", middleware.CORS(imageHandler.GetImage))
+	http.HandleFunc("/products/{id}/duplicates", middleware.CORS(productHandler.GetNearDuplicates))
+	// Not wrapped in CORS: it is for monitoring, not for browsers.
+	http.HandleFunc("/metrics/janitor", janitorHandler.Metrics)
 
 	port := ":8080"
 	fmt.Printf("Server starting on http://localhost%s\n", port)
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrSessionProtected = errors.New("session is already protected")
+
+// GetSessionAccess returns what the credentials of userID, which may be
+// empty, are checked against. Unlike the other reads it also finds deleted
+// and expired sessions, so they can be restored.
+func (c *Client) GetSessionAccess(ctx context.Context, sessionID, userID string) (*models.SessionAccess, error) {
+	var access models.SessionAccess
+	err := c.Pool.QueryRow(ctx, `
+		SELECT s.protected, s.view_token_hash, s.edit_token_hash, s.passcode_hash, COALESCE(s.owner_id, ''),
+			COALESCE(wm.role, ''), s.deleted_at, s.expires_at
+		FROM sessions s
+		LEFT JOIN workspace_members wm ON wm.workspace_id = s.workspace_id AND wm.user_id = $2
+		WHERE s.id = $1
+	`, sessionID, userID).Scan(
+		&access.Protected,
+		&access.ViewTokenHash,
+		&access.EditTokenHash,
+		&access.PasscodeHash,
+		&access.OwnerId,
+		&access.WorkspaceRole,
+		&access.DeletedAt,
+		&access.ExpiresAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session access: %w", err)
+	}
+
+	return &access, nil
+}
+
+// ClaimSession protects a session created before tokens existed. It returns
+// ErrSessionProtected if the session already has tokens.
+func (c *Client) ClaimSession(ctx context.Context, sessionID string, access *models.SessionAccess) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET protected = TRUE, view_token_hash = $2, edit_token_hash = $3
+		WHERE id = $1 AND NOT protected AND `+liveSession+`
+	`, sessionID, access.ViewTokenHash, access.EditTokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to claim session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := c.GetSessionMetadata(ctx, sessionID); err != nil {
+			return err
+		}
+		return ErrSessionProtected
+	}
+	return nil
+}
+
+// SetSessionViewToken replaces the view token hash; nil revokes view links.
+func (c *Client) SetSessionViewToken(ctx context.Context, sessionID string, hash []byte) error {
+	return c.setSessionSecret(ctx, "view_token_hash", sessionID, hash)
+}
+
+// SetSessionEditToken replaces the edit token hash.
+func (c *Client) SetSessionEditToken(ctx context.Context, sessionID string, hash []byte) error {
+	return c.setSessionSecret(ctx, "edit_token_hash", sessionID, hash)
+}
+
+// SetSessionPasscode replaces the passcode hash; nil removes the passcode.
+func (c *Client) SetSessionPasscode(ctx context.Context, sessionID string, hash []byte) error {
+	return c.setSessionSecret(ctx, "passcode_hash", sessionID, hash)
+}
+
+func (c *Client) setSessionSecret(ctx context.Context, column, sessionID string, hash []byte) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET `+column+` = $2
+		WHERE id = $1 AND `+liveSession+`
+	`, sessionID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to update session secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
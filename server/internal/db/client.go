@@ -2,16 +2,30 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net"
 	"server/internal/models"
 	"time"
 
 	"cloud.google.com/go/cloudsqlconn"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExists   = errors.New("session already exists")
+	ErrVersionConflict = errors.New("session version does not match")
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 type Client struct {
 	Pool   *pgxpool.Pool
 	config *Config
@@ -49,6 +63,11 @@ func NewClient(ctx context.Context, config *Config) (*Client, error) {
 	// Set custom dialer
 	poolConfig.ConnConfig.DialFunc = dialerFunc
 
+	// Migrations report what they changed with RAISE NOTICE.
+	poolConfig.ConnConfig.OnNotice = func(_ *pgconn.PgConn, notice *pgconn.Notice) {
+		log.Printf("Database notice: %s", notice.Message)
+	}
+
 	// Configure pool settings
 	poolConfig.MaxConns = int32(config.MaxOpenConns)
 	poolConfig.MinConns = int32(config.MaxIdleConns)
@@ -78,89 +97,383 @@ func (c *Client) Close() {
 	}
 }
 
-func (c *Client) SaveSession(ctx context.Context, req *models.SaveSessionRequest) error {
+// CreateSession inserts a new, empty, protected session whose tokens hash to
+// the given values, owned by access.OwnerId if set. It returns
+// ErrSessionExists if the ID is already taken.
+func (c *Client) CreateSession(ctx context.Context, session *models.Session, access *models.SessionAccess) error {
+	query := `
+		INSERT INTO sessions (
+			id, title, description, device_id, settings, view_token_hash, edit_token_hash, expires_at,
+			owner_id, tags, workspace_id
+		)
+		VALUES ($1, $2, $3, $4, '{}', $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''))
+		RETURNING settings, version, created_at, updated_at
+	`
+
+	err := c.Pool.QueryRow(ctx, query,
+		session.Id,
+		session.Title,
+		session.Description,
+		session.DeviceId,
+		access.ViewTokenHash,
+		access.EditTokenHash,
+		session.ExpiresAt,
+		access.OwnerId,
+		session.Tags,
+		session.WorkspaceId,
+	).Scan(&session.Settings, &session.Version, &session.CreatedAt, &session.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSessionExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSession replaces the surfaces and placements of an existing session
+// with the ones in req.Surfaces, records the result as a revision and returns
+// the new version. Unless expectedVersion is 0, the save only succeeds if the
+// stored version still matches it; otherwise it returns ErrVersionConflict. It
+// returns ErrSessionNotFound if the session was never created.
+func (c *Client) SaveSession(ctx context.Context, req *models.SaveSessionRequest, expectedVersion int) (int, error) {
 	// Start a transaction
 	tx, err := c.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Delete existing stickers for this session
-	_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE session_id = $1", req.SessionId)
+	// The session's device follows its first surface.
+	deviceID := ""
+	if len(req.Surfaces) > 0 {
+		deviceID = req.Surfaces[0].DeviceId
+	}
+
+	var settings any
+	if req.Settings != nil {
+		settings = req.Settings
+	}
+
+	var version int
+	snapshot := models.SessionSnapshot{Surfaces: req.Surfaces}
+	err = tx.QueryRow(ctx, `
+		UPDATE sessions SET
+			title = COALESCE($2, title),
+			description = COALESCE($3, description),
+			device_id = COALESCE(NULLIF($4, ''), device_id),
+			settings = COALESCE($5::jsonb, settings),
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($6 = 0 OR version = $6)
+		RETURNING version, title, description, settings
+	`, req.SessionId, req.Title, req.Description, deviceID, settings, expectedVersion).Scan(
+		&version,
+		&snapshot.Title,
+		&snapshot.Description,
+		&snapshot.Settings,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, sessionUpdateError(ctx, tx, req.SessionId)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	// Delete existing surfaces for this session, which cascades to stickers
+	_, err = tx.Exec(ctx, "DELETE FROM session_surfaces WHERE session_id = $1", req.SessionId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete existing surfaces: %w", err)
+	}
+
+	if err := insertSurfaces(ctx, tx, req.SessionId, req.Surfaces); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO session_revisions (session_id, version, author, snapshot)
+		VALUES ($1, $2, $3, $4)
+	`, req.SessionId, version, req.Author, snapshot)
 	if err != nil {
-		return fmt.Errorf("failed to delete existing stickers: %w", err)
+		return 0, fmt.Errorf("failed to record revision: %w", err)
 	}
 
-	// Insert all stickers in a single query
-	if len(req.Stickers) > 0 {
-		query := `
-			INSERT INTO sessions (session_id, sticker_id, url, width, height, x, y)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`
+	err = appendSessionEvent(ctx, tx, req.SessionId, version, models.SessionEvent{
+		Type: models.SessionEventLayoutReplaced,
+		Data: models.SessionEventData{Surfaces: req.Surfaces},
+	})
+	if err != nil {
+		return 0, err
+	}
 
-		batch := &pgx.Batch{}
-		for _, sticker := range req.Stickers {
-			batch.Queue(query,
-				req.SessionId,
+	// Commit the transaction
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetSession returns the session metadata and its surfaces in order, each
+// with its stickers sorted bottom to top. Only Session and Surfaces are filled
+// in; Session is nil if the session does not exist.
+func (c *Client) GetSession(ctx context.Context, sessionID string) (*models.GetSessionDataResponse, error) {
+	session, err := c.GetSessionMetadata(ctx, sessionID)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return nil, err
+	}
+
+	surfaces, err := loadSurfaces(ctx, c.Pool, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.GetSessionDataResponse{
+		Session:  session,
+		Surfaces: surfaces,
+	}, nil
+}
+
+// insertSurfaces inserts the surfaces, in order, and their stickers.
+func insertSurfaces(ctx context.Context, tx pgx.Tx, sessionID string, surfaces []models.SessionSurface) error {
+	surfaceQuery := `
+		INSERT INTO session_surfaces (session_id, surface_id, name, device_id, variant_id, position)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`
+
+	stickerQuery := `
+		INSERT INTO session_stickers (
+			session_id, surface_id, sticker_id, url, width, height, x, y, source, z_index,
+			rotation, scale, flip_x, flip_y, opacity, title
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	// Insert all surfaces and stickers in a single batch
+	batch := &pgx.Batch{}
+	for i, surface := range surfaces {
+		batch.Queue(surfaceQuery,
+			sessionID,
+			surface.Id,
+			surface.Name,
+			surface.DeviceId,
+			surface.DeviceVariant,
+			i,
+		)
+
+		for _, sticker := range surface.Stickers {
+			batch.Queue(stickerQuery,
+				sessionID,
+				surface.Id,
 				sticker.StickerId,
 				sticker.URL,
 				sticker.Size.Width,
 				sticker.Size.Height,
 				sticker.Position.X,
 				sticker.Position.Y,
+				sticker.Source,
+				sticker.ZIndex,
+				sticker.Transform.Rotation,
+				sticker.Transform.Scale,
+				sticker.Transform.FlipX,
+				sticker.Transform.FlipY,
+				sticker.Transform.Opacity,
+				sticker.Title,
 			)
 		}
+	}
 
+	if batch.Len() > 0 {
 		br := tx.SendBatch(ctx, batch)
 		if err := br.Close(); err != nil {
-			return fmt.Errorf("failed to insert stickers: %w", err)
+			return fmt.Errorf("failed to insert surfaces and stickers: %w", err)
 		}
 	}
 
-	// Commit the transaction
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return nil
 }
 
-func (c *Client) GetSession(ctx context.Context, sessionID string) (*models.GetSessionDataResponse, error) {
-	query := `
-		SELECT sticker_id, url, width, height, x, y
-		FROM sessions
+// loadSurfaces returns the surfaces of a session in order, each with its
+// stickers sorted bottom to top.
+func loadSurfaces(ctx context.Context, q querier, sessionID string) ([]models.SessionSurface, error) {
+	surfaceQuery := `
+		SELECT surface_id, name, device_id, COALESCE(variant_id, '')
+		FROM session_surfaces
 		WHERE session_id = $1
+		ORDER BY position, surface_id
 	`
 
-	rows, err := c.Pool.Query(ctx, query, sessionID)
+	rows, err := q.Query(ctx, surfaceQuery, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session surfaces: %w", err)
+	}
+	defer rows.Close()
+
+	surfaces := []models.SessionSurface{}
+	surfaceIndex := make(map[string]int)
+	for rows.Next() {
+		var surface models.SessionSurface
+		err := rows.Scan(
+			&surface.Id,
+			&surface.Name,
+			&surface.DeviceId,
+			&surface.DeviceVariant,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		surface.Stickers = []models.SavedStickerData{}
+		surfaceIndex[surface.Id] = len(surfaces)
+		surfaces = append(surfaces, surface)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	stickerQuery := `
+		SELECT surface_id, sticker_id, url, width, height, x, y, source, z_index,
+			rotation, scale, flip_x, flip_y, opacity, title
+		FROM session_stickers
+		WHERE session_id = $1
+		ORDER BY surface_id, z_index, id
+	`
+
+	rows, err = q.Query(ctx, stickerQuery, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query session: %w", err)
 	}
 	defer rows.Close()
 
-	var stickers []models.SavedStickerData
 	for rows.Next() {
+		var surfaceID string
 		var sticker models.SavedStickerData
 		err := rows.Scan(
+			&surfaceID,
 			&sticker.StickerId,
 			&sticker.URL,
 			&sticker.Size.Width,
 			&sticker.Size.Height,
 			&sticker.Position.X,
 			&sticker.Position.Y,
+			&sticker.Source,
+			&sticker.ZIndex,
+			&sticker.Transform.Rotation,
+			&sticker.Transform.Scale,
+			&sticker.Transform.FlipX,
+			&sticker.Transform.FlipY,
+			&sticker.Transform.Opacity,
+			&sticker.Title,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-		stickers = append(stickers, sticker)
+
+		i, ok := surfaceIndex[surfaceID]
+		if !ok {
+			continue
+		}
+		surfaces[i].Stickers = append(surfaces[i].Stickers, sticker)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return &models.GetSessionDataResponse{
-		Stickers: stickers,
-	}, nil
+	return surfaces, nil
+}
+
+func (c *Client) GetSessionMetadata(ctx context.Context, sessionID string) (*models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE id = $1 AND ` + liveSession + `
+	`
+
+	session, err := scanSession(c.Pool.QueryRow(ctx, query, sessionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session: %w", err)
+	}
+
+	return session, nil
+}
+
+// sessionColumns are the columns scanSession expects, in order.
+const sessionColumns = `id, title, description, COALESCE(owner_id, ''), COALESCE(workspace_id, ''), device_id, settings, version,
+	COALESCE(forked_from, ''), tags, expires_at, created_at, updated_at`
+
+func scanSession(row pgx.Row) (*models.Session, error) {
+	var session models.Session
+	err := row.Scan(
+		&session.Id,
+		&session.Title,
+		&session.Description,
+		&session.OwnerId,
+		&session.WorkspaceId,
+		&session.DeviceId,
+		&session.Settings,
+		&session.Version,
+		&session.ForkedFrom,
+		&session.Tags,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionPalettes returns the palette of every sticker in the session:
+// the catalog palette of a product image, matched by URL, or the palette
+// stored with a mirrored /images/{hash} image. Stickers without a known
+// palette are skipped.
+func (c *Client) GetSessionPalettes(ctx context.Context, sessionID string) ([]models.StickerPalette, error) {
+	query := `
+		SELECT s.width, s.height, COALESCE(p.palette, i.palette)
+		FROM session_stickers s
+		LEFT JOIN LATERAL (
+			SELECT palette
+			FROM products
+			WHERE image_url = s.url AND palette IS NOT NULL
+			ORDER BY updated_at DESC
+			LIMIT 1
+		) p ON true
+		LEFT JOIN images i
+			ON s.url LIKE '/images/%'
+			AND i.hash = split_part(substring(s.url FROM 9), '?', 1)
+		WHERE s.session_id = $1
+			AND COALESCE(p.palette, i.palette) IS NOT NULL
+	`
+
+	rows, err := c.Pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session palettes: %w", err)
+	}
+	defer rows.Close()
+
+	var palettes []models.StickerPalette
+	for rows.Next() {
+		var palette models.StickerPalette
+		err := rows.Scan(
+			&palette.Size.Width,
+			&palette.Size.Height,
+			&palette.Palette,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		palettes = append(palettes, palette)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return palettes, nil
 }
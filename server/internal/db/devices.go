@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+const deviceColumns = `id, name, lid_width, lid_height, surface, keep_out_zones, variants`
+
+func scanDevice(row pgx.Row, device *models.Device) error {
+	return row.Scan(
+		&device.ID,
+		&device.Name,
+		&device.LidWidth,
+		&device.LidHeight,
+		&device.Surface,
+		&device.KeepOutZones,
+		&device.Variants,
+	)
+}
+
+func (c *Client) ListDevices(ctx context.Context) ([]models.Device, error) {
+	query := `SELECT ` + deviceColumns + ` FROM devices ORDER BY sort_order, id`
+
+	rows, err := c.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+	for rows.Next() {
+		var device models.Device
+		if err := scanDevice(rows, &device); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return devices, nil
+}
+
+func (c *Client) GetDevice(ctx context.Context, id string) (*models.Device, error) {
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE id = $1`
+
+	var device models.Device
+	err := scanDevice(c.Pool.QueryRow(ctx, query, id), &device)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device: %w", err)
+	}
+
+	return &device, nil
+}
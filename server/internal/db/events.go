@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"server/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// appendSessionEvent adds an entry to the session's edit log inside the
+// transaction that made the change.
+func appendSessionEvent(ctx context.Context, tx pgx.Tx, sessionID string, version int, event models.SessionEvent) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO session_events (session_id, version, type, surface_id, sticker_id, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, sessionID, version, event.Type, event.SurfaceId, event.StickerId, event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to append session event: %w", err)
+	}
+	return nil
+}
+
+// ListSessionEvents returns the edit log of a session in order, from after
+// afterVersion up to and including until. A zero afterVersion or until leaves
+// that end open.
+func (c *Client) ListSessionEvents(ctx context.Context, sessionID string, afterVersion int, until time.Time) ([]models.SessionEvent, error) {
+	if _, err := c.GetSessionMetadata(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	var bound *time.Time
+	if !until.IsZero() {
+		bound = &until
+	}
+
+	rows, err := c.Pool.Query(ctx, `
+		SELECT id, version, type, surface_id, sticker_id, data, created_at
+		FROM session_events
+		WHERE session_id = $1 AND version > $2 AND ($3::timestamptz IS NULL OR created_at <= $3)
+		ORDER BY id
+	`, sessionID, afterVersion, bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.SessionEvent{}
+	for rows.Next() {
+		var event models.SessionEvent
+		err := rows.Scan(
+			&event.Id,
+			&event.Version,
+			&event.Type,
+			&event.SurfaceId,
+			&event.StickerId,
+			&event.Data,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Forks are followed at most this many levels up when listing ancestors.
+const maxLineageDepth = 100
+
+// ForkSession copies the metadata, device, surfaces and placements of the
+// source session into a new session with the given ID that records where it
+// was forked from. The fork starts at version 1 with its own first revision
+// and is protected by new tokens. It belongs to access.OwnerId if set, not to
+// the owner of the source.
+// It returns ErrSessionExists if forkID is taken.
+func (c *Client) ForkSession(ctx context.Context, sourceID, forkID string, title *string, author string, access *models.SessionAccess) (*models.Session, error) {
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// FOR SHARE waits for saves in progress so the copy is consistent.
+	fork, err := scanSession(tx.QueryRow(ctx, `
+		WITH source AS (
+			SELECT * FROM sessions WHERE id = $1 AND `+liveSession+` FOR SHARE
+		)
+		INSERT INTO sessions (id, title, description, device_id, settings, tags, forked_from, view_token_hash, edit_token_hash, owner_id)
+		SELECT $2, COALESCE($3, title), description, device_id, settings, tags, id, $4, $5, NULLIF($6, '')
+		FROM source
+		RETURNING `+sessionColumns,
+		sourceID, forkID, title, access.ViewTokenHash, access.EditTokenHash, access.OwnerId,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrSessionExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fork: %w", err)
+	}
+
+	surfaces, err := loadSurfaces(ctx, tx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertSurfaces(ctx, tx, forkID, surfaces); err != nil {
+		return nil, err
+	}
+
+	snapshot := models.SessionSnapshot{
+		Title:       fork.Title,
+		Description: fork.Description,
+		Settings:    fork.Settings,
+		Surfaces:    surfaces,
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO session_revisions (session_id, version, author, snapshot)
+		VALUES ($1, $2, $3, $4)
+	`, forkID, fork.Version, author, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	err = appendSessionEvent(ctx, tx, forkID, fork.Version, models.SessionEvent{
+		Type: models.SessionEventLayoutReplaced,
+		Data: models.SessionEventData{Surfaces: surfaces},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return fork, nil
+}
+
+// GetSessionLineage returns the sessions the given one descends from,
+// nearest first, and its direct forks, oldest first.
+func (c *Client) GetSessionLineage(ctx context.Context, sessionID string) (ancestors, forks []models.Session, err error) {
+	if _, err := c.GetSessionMetadata(ctx, sessionID); err != nil {
+		return nil, nil, err
+	}
+
+	ancestors, err = c.querySessions(ctx, `
+		WITH RECURSIVE ancestors (session_id, depth) AS (
+			SELECT forked_from, 1 FROM sessions WHERE id = $1 AND forked_from IS NOT NULL
+			UNION ALL
+			SELECT s.forked_from, a.depth + 1
+			FROM ancestors a
+			JOIN sessions s ON s.id = a.session_id
+			WHERE s.forked_from IS NOT NULL AND a.depth < $2
+		)
+		SELECT `+sessionColumns+`
+		FROM ancestors
+		JOIN sessions ON sessions.id = ancestors.session_id
+		WHERE `+liveSession+`
+		ORDER BY ancestors.depth
+	`, sessionID, maxLineageDepth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	forks, err = c.querySessions(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE forked_from = $1 AND `+liveSession+`
+		ORDER BY created_at, id
+	`, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ancestors, forks, nil
+}
+
+// querySessions runs a query selecting sessionColumns and scans every row.
+func (c *Client) querySessions(ctx context.Context, query string, args ...any) ([]models.Session, error) {
+	rows, err := c.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrImageNotFound = errors.New("image not found")
+
+func (c *Client) SaveImage(ctx context.Context, img *models.Image) error {
+	query := `
+		INSERT INTO images (hash, content_type, width, height, data, source_url, palette)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (hash) DO UPDATE SET palette = COALESCE(images.palette, EXCLUDED.palette)
+	`
+
+	_, err := c.Pool.Exec(ctx, query,
+		img.Hash,
+		img.ContentType,
+		img.Width,
+		img.Height,
+		img.Data,
+		img.SourceURL,
+		img.Palette,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) GetImage(ctx context.Context, hash string) (*models.Image, error) {
+	query := `
+		SELECT hash, content_type, width, height, data, COALESCE(source_url, ''), palette
+		FROM images
+		WHERE hash = $1
+	`
+
+	var img models.Image
+	err := c.Pool.QueryRow(ctx, query, hash).Scan(
+		&img.Hash,
+		&img.ContentType,
+		&img.Width,
+		&img.Height,
+		&img.Data,
+		&img.SourceURL,
+		&img.Palette,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image: %w", err)
+	}
+
+	return &img, nil
+}
+
+func (c *Client) SaveImageDerivative(ctx context.Context, hash string, width int, contentType string, data []byte) error {
+	query := `
+		INSERT INTO image_derivatives (hash, width, content_type, data)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (hash, width) DO NOTHING
+	`
+
+	_, err := c.Pool.Exec(ctx, query, hash, width, contentType, data)
+	if err != nil {
+		return fmt.Errorf("failed to save image derivative: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) GetImageDerivative(ctx context.Context, hash string, width int) (string, []byte, error) {
+	query := `
+		SELECT content_type, data
+		FROM image_derivatives
+		WHERE hash = $1 AND width = $2
+	`
+
+	var contentType string
+	var data []byte
+	err := c.Pool.QueryRow(ctx, query, hash, width).Scan(&contentType, &data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, ErrImageNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to query image derivative: %w", err)
+	}
+
+	return contentType, data, nil
+}
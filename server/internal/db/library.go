@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrLibraryItemNotFound = errors.New("library item not found")
+
+// libraryItemColumns are the columns scanLibraryItem expects, in order.
+const libraryItemColumns = `id, COALESCE(owner_id, ''), COALESCE(workspace_id, ''), COALESCE(created_by, ''),
+	title, notes, tags, url, source_url, source, width, height, COALESCE(product_id, 0),
+	COALESCE(image_hash, ''), palette, created_at, updated_at`
+
+func scanLibraryItem(row pgx.Row) (*models.LibraryItem, error) {
+	var item models.LibraryItem
+	err := row.Scan(
+		&item.Id,
+		&item.OwnerId,
+		&item.WorkspaceId,
+		&item.CreatedBy,
+		&item.Title,
+		&item.Notes,
+		&item.Tags,
+		&item.URL,
+		&item.SourceURL,
+		&item.Source,
+		&item.Size.Width,
+		&item.Size.Height,
+		&item.ProductID,
+		&item.ImageHash,
+		&item.Palette,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLibraryItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+	return &item, nil
+}
+
+// CreateLibraryItem inserts the item, filling in its id and timestamps.
+// Exactly one of item.OwnerId and item.WorkspaceId must be set.
+func (c *Client) CreateLibraryItem(ctx context.Context, item *models.LibraryItem) error {
+	// Pass a missing palette as SQL NULL rather than JSON null.
+	var palette any
+	if len(item.Palette) > 0 {
+		palette = item.Palette
+	}
+
+	err := c.Pool.QueryRow(ctx, `
+		INSERT INTO library_items (
+			owner_id, workspace_id, created_by, title, notes, tags, url, source_url, source,
+			width, height, product_id, image_hash, palette
+		)
+		VALUES (
+			NULLIF($1, ''), NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, $9,
+			$10, $11, NULLIF($12, 0), NULLIF($13, ''), $14
+		)
+		RETURNING id, created_at, updated_at
+	`,
+		item.OwnerId,
+		item.WorkspaceId,
+		item.CreatedBy,
+		item.Title,
+		item.Notes,
+		item.Tags,
+		item.URL,
+		item.SourceURL,
+		item.Source,
+		item.Size.Width,
+		item.Size.Height,
+		item.ProductID,
+		item.ImageHash,
+		palette,
+	).Scan(&item.Id, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create library item: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetLibraryItem(ctx context.Context, id int64) (*models.LibraryItem, error) {
+	return scanLibraryItem(c.Pool.QueryRow(ctx, `
+		SELECT `+libraryItemColumns+`
+		FROM library_items
+		WHERE id = $1
+	`, id))
+}
+
+// UpdateLibraryItem stores the title, notes and tags of the item and fills
+// in its new updated_at.
+func (c *Client) UpdateLibraryItem(ctx context.Context, item *models.LibraryItem) error {
+	err := c.Pool.QueryRow(ctx, `
+		UPDATE library_items SET title = $2, notes = $3, tags = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`, item.Id, item.Title, item.Notes, item.Tags).Scan(&item.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrLibraryItemNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update library item: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeleteLibraryItem(ctx context.Context, id int64) error {
+	tag, err := c.Pool.Exec(ctx, `DELETE FROM library_items WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete library item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLibraryItemNotFound
+	}
+	return nil
+}
+
+// LibraryQuery selects the items of a user or, if WorkspaceID is set, of a
+// workspace, newest first. Items with an id of AfterID or above are skipped
+// unless it is 0. Other zero fields do not filter.
+type LibraryQuery struct {
+	OwnerID     string
+	WorkspaceID string
+	Text        string
+	Tags        []string
+	AfterID     int64
+	Limit       int
+}
+
+// ListLibraryItems returns up to query.Limit items. Text matches titles and
+// notes.
+func (c *Client) ListLibraryItems(ctx context.Context, query LibraryQuery) ([]models.LibraryItem, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var conditions []string
+	if query.WorkspaceID != "" {
+		conditions = append(conditions, "workspace_id = "+arg(query.WorkspaceID))
+	} else {
+		conditions = append(conditions, "owner_id = "+arg(query.OwnerID))
+	}
+	if query.Text != "" {
+		conditions = append(conditions,
+			"to_tsvector('english', title || ' ' || notes) @@ websearch_to_tsquery('english', "+arg(query.Text)+")")
+	}
+	if len(query.Tags) > 0 {
+		conditions = append(conditions, "tags @> "+arg(query.Tags)+"::text[]")
+	}
+	if query.AfterID > 0 {
+		conditions = append(conditions, "id < "+arg(query.AfterID))
+	}
+
+	sql := `
+		SELECT ` + libraryItemColumns + `
+		FROM library_items
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id DESC
+		LIMIT ` + arg(query.Limit)
+
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query library items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.LibraryItem{}
+	for rows.Next() {
+		item, err := scanLibraryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// liveSession filters out sessions that were soft-deleted or have expired.
+// Such sessions stay in the table until the janitor purges them but are
+// treated as missing everywhere else.
+const liveSession = `deleted_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+
+// PurgeReason selects which sessions PurgeSessions removes.
+type PurgeReason string
+
+const (
+	// PurgeDeleted removes sessions soft-deleted before the cutoff.
+	PurgeDeleted PurgeReason = "deleted"
+	// PurgeExpired removes sessions that expired before the cutoff.
+	PurgeExpired PurgeReason = "expired"
+	// PurgeAbandoned removes anonymous sessions, with neither an owner nor a
+	// workspace, that have no placed sticker and were last touched before the
+	// cutoff.
+	PurgeAbandoned PurgeReason = "abandoned"
+)
+
+var purgeConditions = map[PurgeReason]string{
+	PurgeDeleted: `deleted_at < $1`,
+	PurgeExpired: `expires_at < $1`,
+	PurgeAbandoned: `deleted_at IS NULL AND updated_at < $1
+		AND owner_id IS NULL AND workspace_id IS NULL
+		AND NOT EXISTS (SELECT 1 FROM session_stickers ss WHERE ss.session_id = sessions.id)`,
+}
+
+// SoftDeleteSession marks a session as deleted. It returns ErrSessionNotFound
+// if the session does not exist or is already deleted.
+func (c *Client) SoftDeleteSession(ctx context.Context, sessionID string) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND `+liveSession+`
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RestoreSession undoes a soft delete made after deletedAfter. It returns
+// ErrSessionNotFound if there is no such deleted session.
+func (c *Client) RestoreSession(ctx context.Context, sessionID string, deletedAfter time.Time) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at > $2
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`, sessionID, deletedAfter)
+	if err != nil {
+		return fmt.Errorf("failed to restore session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// SetSessionExpiry sets when a session expires; nil removes the expiry.
+func (c *Client) SetSessionExpiry(ctx context.Context, sessionID string, expiresAt *time.Time) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET expires_at = $2
+		WHERE id = $1 AND `+liveSession+`
+	`, sessionID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to set session expiry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// SetSessionTags replaces the tags of a session.
+func (c *Client) SetSessionTags(ctx context.Context, sessionID string, tags []string) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET tags = $2
+		WHERE id = $1 AND `+liveSession+`
+	`, sessionID, tags)
+	if err != nil {
+		return fmt.Errorf("failed to set session tags: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// PurgeSessions hard-deletes up to limit sessions matching the reason and
+// returns how many were removed. Their surfaces, placements, revisions and
+// events go with them; forks keep existing without their forked_from link.
+// Rows locked by a concurrent write are skipped until the next call.
+func (c *Client) PurgeSessions(ctx context.Context, reason PurgeReason, cutoff time.Time, limit int) (int64, error) {
+	condition, ok := purgeConditions[reason]
+	if !ok {
+		return 0, fmt.Errorf("unknown purge reason %q", reason)
+	}
+
+	tag, err := c.Pool.Exec(ctx, `
+		DELETE FROM sessions
+		WHERE id IN (
+			SELECT id FROM sessions
+			WHERE `+condition+`
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge %s sessions: %w", reason, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -46,6 +46,578 @@ func (c *Client) InitializeDatabase(ctx context.Context) error {
 				ALTER COLUMN height TYPE FLOAT;
 			`,
 		},
+		{
+			Version:     4,
+			Description: "Create images and image_derivatives tables",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS images (
+					hash VARCHAR(64) PRIMARY KEY,
+					content_type VARCHAR(100) NOT NULL,
+					width INTEGER NOT NULL,
+					height INTEGER NOT NULL,
+					data BYTEA NOT NULL,
+					source_url VARCHAR(2000),
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS image_derivatives (
+					hash VARCHAR(64) NOT NULL REFERENCES images(hash) ON DELETE CASCADE,
+					width INTEGER NOT NULL,
+					content_type VARCHAR(100) NOT NULL,
+					data BYTEA NOT NULL,
+					PRIMARY KEY (hash, width)
+				);
+			`,
+		},
+		{
+			Version:     5,
+			Description: "Create products catalog table",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS products (
+					id SERIAL PRIMARY KEY,
+					source_url VARCHAR(2000) NOT NULL UNIQUE,
+					image_url VARCHAR(2000) NOT NULL,
+					image_hash VARCHAR(64) REFERENCES images(hash),
+					width FLOAT,
+					height FLOAT,
+					phash BIGINT,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+			`,
+		},
+		{
+			Version:     6,
+			Description: "Add palette column to products",
+			SQL: `
+				ALTER TABLE products
+				ADD COLUMN IF NOT EXISTS palette JSONB;
+
+				CREATE INDEX IF NOT EXISTS idx_products_image_url ON products (image_url);
+			`,
+		},
+		{
+			Version:     7,
+			Description: "Add source column to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'stickermule';
+			`,
+		},
+		{
+			Version:     8,
+			Description: "Add z_index column to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS z_index INTEGER NOT NULL DEFAULT 0;
+
+				-- Stickers were inserted bottom to top, so insertion order is the layer order.
+				UPDATE sessions s
+				SET z_index = ordered.z_index
+				FROM (
+					SELECT id, ROW_NUMBER() OVER (PARTITION BY session_id ORDER BY id) - 1 AS z_index
+					FROM sessions
+				) ordered
+				WHERE s.id = ordered.id;
+
+				CREATE INDEX IF NOT EXISTS idx_sessions_session_id_z_index ON sessions (session_id, z_index);
+			`,
+		},
+		{
+			Version:     9,
+			Description: "Add transform columns to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS rotation FLOAT NOT NULL DEFAULT 0,
+				ADD COLUMN IF NOT EXISTS scale FLOAT NOT NULL DEFAULT 1,
+				ADD COLUMN IF NOT EXISTS flip_x BOOLEAN NOT NULL DEFAULT FALSE,
+				ADD COLUMN IF NOT EXISTS flip_y BOOLEAN NOT NULL DEFAULT FALSE,
+				ADD COLUMN IF NOT EXISTS opacity FLOAT NOT NULL DEFAULT 1;
+			`,
+		},
+		{
+			Version:     10,
+			Description: "Store positions as inches relative to the device surface",
+			SQL: `
+				-- Pixel positions were saved by a client rendering the 12.3in lid
+				-- 900px wide.
+				ALTER TABLE sessions
+				ALTER COLUMN x TYPE FLOAT USING x * 12.3 / 900,
+				ALTER COLUMN y TYPE FLOAT USING y * 12.3 / 900;
+			`,
+		},
+		{
+			Version:     11,
+			Description: "Create devices catalog and session_devices tables",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS devices (
+					id VARCHAR(64) PRIMARY KEY,
+					name VARCHAR(255) NOT NULL,
+					lid_width FLOAT NOT NULL,
+					lid_height FLOAT NOT NULL,
+					surface JSONB NOT NULL,
+					keep_out_zones JSONB NOT NULL DEFAULT '[]',
+					variants JSONB NOT NULL DEFAULT '[]',
+					sort_order INTEGER NOT NULL DEFAULT 0
+				);
+
+				INSERT INTO devices (id, name, lid_width, lid_height, surface, keep_out_zones, variants, sort_order)
+				VALUES
+				(
+					'macbook-pro-14', '14" MacBook Pro', 12.3, 8.5,
+					'[{"x":0,"y":0},{"x":12.3,"y":0},{"x":12.3,"y":8.5},{"x":0,"y":8.5}]',
+					'[{"name":"Apple logo","polygon":[{"x":5.45,"y":3.4},{"x":6.85,"y":3.4},{"x":6.85,"y":5.1},{"x":5.45,"y":5.1}]}]',
+					'[{"id":"space-black","name":"Space Black","color":"#2e2c2f","backgroundImage":"/mbp14-black.jpeg"},{"id":"silver","name":"Silver","color":"#e3e4e5"}]',
+					10
+				),
+				(
+					'macbook-pro-16', '16" MacBook Pro', 14.01, 9.77,
+					'[{"x":0,"y":0},{"x":14.01,"y":0},{"x":14.01,"y":9.77},{"x":0,"y":9.77}]',
+					'[{"name":"Apple logo","polygon":[{"x":6.2,"y":3.9},{"x":7.81,"y":3.9},{"x":7.81,"y":5.87},{"x":6.2,"y":5.87}]}]',
+					'[{"id":"space-black","name":"Space Black","color":"#2e2c2f"},{"id":"silver","name":"Silver","color":"#e3e4e5"}]',
+					20
+				),
+				(
+					'macbook-air-13', '13" MacBook Air', 11.97, 8.46,
+					'[{"x":0,"y":0},{"x":11.97,"y":0},{"x":11.97,"y":8.46},{"x":0,"y":8.46}]',
+					'[{"name":"Apple logo","polygon":[{"x":5.34,"y":3.45},{"x":6.63,"y":3.45},{"x":6.63,"y":5.01},{"x":5.34,"y":5.01}]}]',
+					'[{"id":"midnight","name":"Midnight","color":"#2e3642"},{"id":"starlight","name":"Starlight","color":"#f0e4d3"},{"id":"space-gray","name":"Space Gray","color":"#7d7e80"},{"id":"silver","name":"Silver","color":"#e3e4e5"}]',
+					30
+				),
+				(
+					'dell-xps-13', 'Dell XPS 13', 11.63, 7.84,
+					'[{"x":0,"y":0},{"x":11.63,"y":0},{"x":11.63,"y":7.84},{"x":0,"y":7.84}]',
+					'[{"name":"Dell logo","polygon":[{"x":5.27,"y":3.37},{"x":6.36,"y":3.37},{"x":6.36,"y":4.47},{"x":5.27,"y":4.47}]}]',
+					'[{"id":"platinum","name":"Platinum","color":"#d9d9d6"},{"id":"graphite","name":"Graphite","color":"#3b3b3d"}]',
+					40
+				),
+				(
+					'dell-xps-15', 'Dell XPS 15', 13.57, 9.06,
+					'[{"x":0,"y":0},{"x":13.57,"y":0},{"x":13.57,"y":9.06},{"x":0,"y":9.06}]',
+					'[{"name":"Dell logo","polygon":[{"x":6.19,"y":3.98},{"x":7.38,"y":3.98},{"x":7.38,"y":5.08},{"x":6.19,"y":5.08}]}]',
+					'[{"id":"platinum","name":"Platinum","color":"#d9d9d6"}]',
+					50
+				)
+				ON CONFLICT (id) DO NOTHING;
+
+				CREATE TABLE IF NOT EXISTS session_devices (
+					session_id VARCHAR(255) PRIMARY KEY,
+					device_id VARCHAR(64) NOT NULL REFERENCES devices(id),
+					variant_id VARCHAR(64)
+				);
+			`,
+		},
+		{
+			Version:     12,
+			Description: "Create session_surfaces table and add surface_id to sessions",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS session_surfaces (
+					session_id VARCHAR(255) NOT NULL,
+					surface_id VARCHAR(64) NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					device_id VARCHAR(64) NOT NULL REFERENCES devices(id),
+					variant_id VARCHAR(64),
+					position INTEGER NOT NULL DEFAULT 0,
+					PRIMARY KEY (session_id, surface_id)
+				);
+
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS surface_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+				-- Every existing session becomes a single surface on the device it
+				-- had selected, or the original 14" MacBook Pro.
+				INSERT INTO session_surfaces (session_id, surface_id, name, device_id, variant_id, position)
+				SELECT ids.session_id, 'default', d.name, d.id, sd.variant_id, 0
+				FROM (
+					SELECT session_id FROM sessions
+					UNION
+					SELECT session_id FROM session_devices
+				) ids
+				LEFT JOIN session_devices sd ON sd.session_id = ids.session_id
+				JOIN devices d ON d.id = COALESCE(sd.device_id, 'macbook-pro-14')
+				ON CONFLICT DO NOTHING;
+
+				DROP TABLE IF EXISTS session_devices;
+
+				DROP INDEX IF EXISTS idx_sessions_session_id_z_index;
+				CREATE INDEX IF NOT EXISTS idx_sessions_surface_z_index ON sessions (session_id, surface_id, z_index);
+			`,
+		},
+		{
+			Version:     13,
+			Description: "Move placements to session_stickers and create sessions entity table",
+			SQL: `
+				ALTER TABLE sessions RENAME TO session_stickers;
+				ALTER SEQUENCE sessions_id_seq RENAME TO session_stickers_id_seq;
+				ALTER INDEX sessions_pkey RENAME TO session_stickers_pkey;
+				ALTER INDEX idx_sessions_surface_z_index RENAME TO idx_session_stickers_surface_z_index;
+
+				CREATE TABLE sessions (
+					id VARCHAR(255) PRIMARY KEY,
+					title VARCHAR(255) NOT NULL DEFAULT '',
+					description TEXT NOT NULL DEFAULT '',
+					owner_id VARCHAR(255),
+					device_id VARCHAR(64) NOT NULL DEFAULT 'macbook-pro-14' REFERENCES devices(id),
+					settings JSONB NOT NULL DEFAULT '{}',
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);
+
+				-- The device of the first surface becomes the session's device.
+				INSERT INTO sessions (id, device_id)
+				SELECT DISTINCT ON (session_id) session_id, device_id
+				FROM session_surfaces
+				ORDER BY session_id, position;
+
+				INSERT INTO sessions (id)
+				SELECT DISTINCT session_id FROM session_stickers
+				ON CONFLICT (id) DO NOTHING;
+
+				INSERT INTO session_surfaces (session_id, surface_id, name, device_id, position)
+				SELECT DISTINCT st.session_id, st.surface_id, d.name, d.id, 0
+				FROM session_stickers st
+				JOIN devices d ON d.id = 'macbook-pro-14'
+				ON CONFLICT DO NOTHING;
+
+				ALTER TABLE session_surfaces
+				ADD CONSTRAINT session_surfaces_session_id_fkey
+				FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
+
+				ALTER TABLE session_stickers
+				ADD CONSTRAINT session_stickers_surface_fkey
+				FOREIGN KEY (session_id, surface_id) REFERENCES session_surfaces(session_id, surface_id) ON DELETE CASCADE;
+			`,
+		},
+		{
+			Version:     14,
+			Description: "Add version column to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+			`,
+		},
+		{
+			Version:     15,
+			Description: "Make sticker ids unique per surface",
+			SQL: `
+				-- Keep the most recently inserted copy of any duplicated placement
+				-- and report how many were dropped.
+				DO $$
+				DECLARE
+					removed INTEGER;
+				BEGIN
+					DELETE FROM session_stickers a
+					USING session_stickers b
+					WHERE a.session_id = b.session_id
+						AND a.surface_id = b.surface_id
+						AND a.sticker_id = b.sticker_id
+						AND a.id < b.id;
+
+					GET DIAGNOSTICS removed = ROW_COUNT;
+					IF removed > 0 THEN
+						RAISE NOTICE 'Removed % duplicate sticker placements', removed;
+					END IF;
+				END $$;
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_session_stickers_sticker_id
+				ON session_stickers(session_id, surface_id, sticker_id);
+			`,
+		},
+		{
+			Version:     16,
+			Description: "Create session_revisions table",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS session_revisions (
+					session_id VARCHAR(255) NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+					version INTEGER NOT NULL,
+					author VARCHAR(255) NOT NULL DEFAULT '',
+					snapshot JSONB NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (session_id, version)
+				);
+			`,
+		},
+		{
+			Version:     17,
+			Description: "Create session_events table",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS session_events (
+					id BIGSERIAL PRIMARY KEY,
+					session_id VARCHAR(255) NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+					version INTEGER NOT NULL,
+					type VARCHAR(32) NOT NULL,
+					surface_id VARCHAR(64) NOT NULL DEFAULT '',
+					sticker_id VARCHAR(255) NOT NULL DEFAULT '',
+					data JSONB NOT NULL DEFAULT '{}',
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_session_events_session_id ON session_events (session_id, id);
+
+				-- Start the log of existing sessions with their current layout.
+				INSERT INTO session_events (session_id, version, type, data, created_at)
+				SELECT s.id, s.version, 'layout_replaced', jsonb_build_object('surfaces', COALESCE((
+					SELECT jsonb_agg(jsonb_build_object(
+						'id', su.surface_id,
+						'name', su.name,
+						'deviceId', su.device_id,
+						'deviceVariant', COALESCE(su.variant_id, ''),
+						'stickers', COALESCE((
+							SELECT jsonb_agg(jsonb_build_object(
+								'stickerId', st.sticker_id,
+								'url', st.url,
+								'size', jsonb_build_object('width', st.width, 'height', st.height),
+								'position', jsonb_build_object('x', st.x, 'y', st.y),
+								'source', st.source,
+								'zIndex', st.z_index,
+								'transform', jsonb_build_object(
+									'rotation', st.rotation,
+									'scale', st.scale,
+									'flipX', st.flip_x,
+									'flipY', st.flip_y,
+									'opacity', st.opacity
+								)
+							) ORDER BY st.z_index, st.id)
+							FROM session_stickers st
+							WHERE st.session_id = su.session_id AND st.surface_id = su.surface_id
+						), '[]'::jsonb)
+					) ORDER BY su.position)
+					FROM session_surfaces su
+					WHERE su.session_id = s.id
+				), '[]'::jsonb)), s.updated_at
+				FROM sessions s;
+			`,
+		},
+		{
+			Version:     18,
+			Description: "Add forked_from column to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS forked_from VARCHAR(255) REFERENCES sessions(id) ON DELETE SET NULL;
+
+				CREATE INDEX IF NOT EXISTS idx_sessions_forked_from ON sessions (forked_from);
+			`,
+		},
+		{
+			Version:     19,
+			Description: "Add access tokens to sessions",
+			SQL: `
+				-- Existing sessions stay open to anyone with the ID until tokens
+				-- are claimed for them; new sessions are protected.
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS protected BOOLEAN NOT NULL DEFAULT FALSE,
+				ADD COLUMN IF NOT EXISTS view_token_hash BYTEA,
+				ADD COLUMN IF NOT EXISTS edit_token_hash BYTEA;
+
+				ALTER TABLE sessions ALTER COLUMN protected SET DEFAULT TRUE;
+			`,
+		},
+		{
+			Version:     20,
+			Description: "Add passcode hash to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS passcode_hash BYTEA;
+			`,
+		},
+		{
+			Version:     21,
+			Description: "Add soft delete and expiry to sessions",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+				ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+				CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at) WHERE deleted_at IS NOT NULL;
+				CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions (expires_at) WHERE expires_at IS NOT NULL;
+				CREATE INDEX IF NOT EXISTS idx_sessions_updated_at ON sessions (updated_at);
+			`,
+		},
+		{
+			Version:     22,
+			Description: "Create users and login sessions, link sessions to owners",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS users (
+					id VARCHAR(64) PRIMARY KEY,
+					email VARCHAR(254) NOT NULL UNIQUE,
+					display_name VARCHAR(255) NOT NULL DEFAULT '',
+					password_hash BYTEA NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS login_sessions (
+					token_hash BYTEA PRIMARY KEY,
+					user_id VARCHAR(64) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					expires_at TIMESTAMPTZ NOT NULL
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_login_sessions_user_id ON login_sessions (user_id);
+
+				-- owner_id was never written, so no existing value names a user.
+				UPDATE sessions SET owner_id = NULL WHERE owner_id IS NOT NULL;
+
+				ALTER TABLE sessions
+				ADD CONSTRAINT sessions_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL;
+
+				CREATE INDEX IF NOT EXISTS idx_sessions_owner_id ON sessions (owner_id);
+			`,
+		},
+		{
+			Version:     23,
+			Description: "Add session tags and sticker titles with search indexes",
+			SQL: `
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+				ALTER TABLE session_stickers
+				ADD COLUMN IF NOT EXISTS title VARCHAR(255) NOT NULL DEFAULT '';
+
+				-- Keyset pagination of a user's sessions for each sort order.
+				-- These cover lookups by owner, so the plain index goes.
+				CREATE INDEX IF NOT EXISTS idx_sessions_owner_updated ON sessions (owner_id, updated_at DESC, id DESC);
+				CREATE INDEX IF NOT EXISTS idx_sessions_owner_created ON sessions (owner_id, created_at DESC, id DESC);
+				CREATE INDEX IF NOT EXISTS idx_sessions_owner_title ON sessions (owner_id, title, id);
+				DROP INDEX IF EXISTS idx_sessions_owner_id;
+
+				CREATE INDEX IF NOT EXISTS idx_sessions_tags ON sessions USING GIN (tags);
+				CREATE INDEX IF NOT EXISTS idx_session_surfaces_device_id ON session_surfaces (device_id);
+
+				-- The expressions must match the ones ListSessions searches with.
+				CREATE INDEX IF NOT EXISTS idx_sessions_search
+					ON sessions USING GIN (to_tsvector('english', title || ' ' || description));
+				CREATE INDEX IF NOT EXISTS idx_session_stickers_search
+					ON session_stickers USING GIN (to_tsvector('english', title));
+			`,
+		},
+		{
+			Version:     24,
+			Description: "Create workspaces and their members, let sessions belong to a workspace",
+			SQL: `
+				CREATE TABLE IF NOT EXISTS workspaces (
+					id VARCHAR(64) PRIMARY KEY,
+					name VARCHAR(255) NOT NULL,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS workspace_members (
+					workspace_id VARCHAR(64) NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+					user_id VARCHAR(64) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (workspace_id, user_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members (user_id);
+
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) REFERENCES workspaces(id) ON DELETE SET NULL;
+
+				CREATE INDEX IF NOT EXISTS idx_sessions_workspace_updated ON sessions (workspace_id, updated_at DESC, id DESC);
+				CREATE INDEX IF NOT EXISTS idx_sessions_workspace_created ON sessions (workspace_id, created_at DESC, id DESC);
+				CREATE INDEX IF NOT EXISTS idx_sessions_workspace_title ON sessions (workspace_id, title, id);
+			`,
+		},
+		{
+			Version:     25,
+			Description: "Create library_items table",
+			SQL: `
+				-- product_id and image_hash only record where the sticker came
+				-- from; the item keeps working if the catalog entry goes away.
+				CREATE TABLE IF NOT EXISTS library_items (
+					id BIGSERIAL PRIMARY KEY,
+					owner_id VARCHAR(64) REFERENCES users(id) ON DELETE CASCADE,
+					workspace_id VARCHAR(64) REFERENCES workspaces(id) ON DELETE CASCADE,
+					created_by VARCHAR(64) REFERENCES users(id) ON DELETE SET NULL,
+					title VARCHAR(255) NOT NULL DEFAULT '',
+					notes TEXT NOT NULL DEFAULT '',
+					tags TEXT[] NOT NULL DEFAULT '{}',
+					url VARCHAR(2000) NOT NULL,
+					source_url VARCHAR(2000) NOT NULL DEFAULT '',
+					source VARCHAR(32) NOT NULL,
+					width FLOAT NOT NULL,
+					height FLOAT NOT NULL,
+					product_id BIGINT,
+					image_hash VARCHAR(64),
+					palette JSONB,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					CHECK ((owner_id IS NULL) <> (workspace_id IS NULL))
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_library_items_owner_id ON library_items (owner_id, id DESC);
+				CREATE INDEX IF NOT EXISTS idx_library_items_workspace_id ON library_items (workspace_id, id DESC);
+				CREATE INDEX IF NOT EXISTS idx_library_items_tags ON library_items USING GIN (tags);
+
+				-- The expression must match the one ListLibraryItems searches with.
+				CREATE INDEX IF NOT EXISTS idx_library_items_search
+					ON library_items USING GIN (to_tsvector('english', title || ' ' || notes));
+			`,
+		},
+		{
+			Version:     26,
+			Description: "Give every device variant a background image",
+			SQL: `
+				-- The client ships /devices/{device}-{variant}.svg for each seeded
+				-- variant. Variants that already have an image keep it.
+				UPDATE devices d SET variants = (
+					SELECT jsonb_agg(
+						CASE WHEN v ? 'backgroundImage' THEN v
+						ELSE v || jsonb_build_object('backgroundImage', '/devices/' || d.id || '-' || (v->>'id') || '.svg')
+						END
+						ORDER BY ord
+					)
+					FROM jsonb_array_elements(d.variants) WITH ORDINALITY AS e(v, ord)
+				)
+				WHERE jsonb_array_length(d.variants) > 0;
+			`,
+		},
+		{
+			Version:     27,
+			Description: "Add palette column to images",
+			SQL: `
+				-- Filled when an image is mirrored, so stickers served from
+				-- /images have a palette without a catalog product. Images
+				-- mirrored earlier get one the next time they are mirrored.
+				ALTER TABLE images
+				ADD COLUMN IF NOT EXISTS palette JSONB;
+			`,
+		},
+		{
+			Version:     28,
+			Description: "Seed the water bottle and notebook devices",
+			SQL: `
+				-- Databases migrated before this seed was split out of
+				-- migration 12 already have these rows and keep them.
+				INSERT INTO devices (id, name, lid_width, lid_height, surface, keep_out_zones, variants, sort_order)
+				VALUES
+				(
+					'water-bottle-24oz', '24oz Water Bottle', 9.4, 7.2,
+					'[{"x":0,"y":0},{"x":9.4,"y":0},{"x":9.4,"y":7.2},{"x":0,"y":7.2}]',
+					'[]',
+					'[{"id":"white","name":"White","color":"#f5f5f5","backgroundImage":"/devices/water-bottle-24oz-white.svg"},{"id":"black","name":"Black","color":"#1f1f1f","backgroundImage":"/devices/water-bottle-24oz-black.svg"}]',
+					60
+				),
+				(
+					'notebook-a5', 'A5 Notebook', 5.83, 8.27,
+					'[{"x":0,"y":0},{"x":5.83,"y":0},{"x":5.83,"y":8.27},{"x":0,"y":8.27}]',
+					'[]',
+					'[{"id":"kraft","name":"Kraft","color":"#c8a27a","backgroundImage":"/devices/notebook-a5-kraft.svg"},{"id":"black","name":"Black","color":"#1f1f1f","backgroundImage":"/devices/notebook-a5-black.svg"}]',
+					70
+				)
+				ON CONFLICT (id) DO NOTHING;
+			`,
+		},
+		{
+			Version:     29,
+			Description: "Add history_start to sessions",
+			SQL: `
+				-- Set when revision retention prunes the edit log, so the
+				-- layout before it is reported as gone rather than empty.
+				ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS history_start TIMESTAMPTZ;
+			`,
+		},
 	}
 
 	for _, migration := range migrations {
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+
+// UpsertProduct records a scraped product in the catalog, keyed by its source
+// URL, and returns its ID. Image fields are only overwritten when known.
+func (c *Client) UpsertProduct(ctx context.Context, product *models.Product) (int64, error) {
+	query := `
+		INSERT INTO products (source_url, image_url, image_hash, width, height, phash, palette)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)
+		ON CONFLICT (source_url) DO UPDATE SET
+			image_url = EXCLUDED.image_url,
+			image_hash = COALESCE(EXCLUDED.image_hash, products.image_hash),
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			phash = COALESCE(EXCLUDED.phash, products.phash),
+			palette = COALESCE(EXCLUDED.palette, products.palette),
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`
+
+	// Pass a missing palette as SQL NULL rather than JSON null so the
+	// existing one is kept.
+	var palette any
+	if len(product.Palette) > 0 {
+		palette = product.Palette
+	}
+
+	var id int64
+	err := c.Pool.QueryRow(ctx, query,
+		product.SourceURL,
+		product.ProductImage,
+		product.ImageHash,
+		product.Size.Width,
+		product.Size.Height,
+		product.PerceptualHash,
+		palette,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert product: %w", err)
+	}
+
+	return id, nil
+}
+
+func (c *Client) GetProduct(ctx context.Context, id int64) (*models.Product, error) {
+	query := `
+		SELECT id, source_url, image_url, COALESCE(image_hash, ''), width, height, phash, palette
+		FROM products
+		WHERE id = $1
+	`
+
+	var product models.Product
+	err := c.Pool.QueryRow(ctx, query, id).Scan(
+		&product.ID,
+		&product.SourceURL,
+		&product.ProductImage,
+		&product.ImageHash,
+		&product.Size.Width,
+		&product.Size.Height,
+		&product.PerceptualHash,
+		&product.Palette,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product: %w", err)
+	}
+
+	return &product, nil
+}
+
+// FindNearDuplicates returns the products whose perceptual hash is within
+// maxDistance bits of phash, closest first.
+func (c *Client) FindNearDuplicates(ctx context.Context, excludeID int64, phash int64, maxDistance int) ([]models.NearDuplicate, error) {
+	query := `
+		SELECT id, source_url, image_url, COALESCE(image_hash, ''), width, height, palette, distance
+		FROM (
+			SELECT *, bit_count((phash # $2)::bit(64)) AS distance
+			FROM products
+			WHERE phash IS NOT NULL AND id <> $1
+		) AS candidates
+		WHERE distance <= $3
+		ORDER BY distance, id
+	`
+
+	rows, err := c.Pool.Query(ctx, query, excludeID, phash, maxDistance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query near duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	duplicates := []models.NearDuplicate{}
+	for rows.Next() {
+		var duplicate models.NearDuplicate
+		err := rows.Scan(
+			&duplicate.ID,
+			&duplicate.SourceURL,
+			&duplicate.ProductImage,
+			&duplicate.ImageHash,
+			&duplicate.Size.Width,
+			&duplicate.Size.Height,
+			&duplicate.Palette,
+			&duplicate.Distance,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return duplicates, nil
+}
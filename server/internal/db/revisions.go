@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrHistoryPruned    = errors.New("history before this time was pruned")
+)
+
+// ListSessionRevisions returns the revisions of a session without their
+// snapshots, newest first.
+func (c *Client) ListSessionRevisions(ctx context.Context, sessionID string) ([]models.SessionRevision, error) {
+	if _, err := c.GetSessionMetadata(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.Pool.Query(ctx, `
+		SELECT version, author, created_at
+		FROM session_revisions
+		WHERE session_id = $1
+		ORDER BY version DESC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.SessionRevision{}
+	for rows.Next() {
+		var revision models.SessionRevision
+		if err := rows.Scan(&revision.Version, &revision.Author, &revision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetSessionRevision returns a revision including its snapshot.
+func (c *Client) GetSessionRevision(ctx context.Context, sessionID string, version int) (*models.SessionRevision, error) {
+	revision := models.SessionRevision{Snapshot: &models.SessionSnapshot{}}
+	err := c.Pool.QueryRow(ctx, `
+		SELECT version, author, created_at, snapshot
+		FROM session_revisions
+		WHERE session_id = $1 AND version = $2
+	`, sessionID, version).Scan(&revision.Version, &revision.Author, &revision.CreatedAt, revision.Snapshot)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revision: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// GetSessionBaseRevision returns the newest revision of a session created at
+// or before at, which the layout at that time is rebuilt from. It returns
+// ErrRevisionNotFound when there is none, and ErrHistoryPruned when at is
+// earlier than the history retention kept.
+func (c *Client) GetSessionBaseRevision(ctx context.Context, sessionID string, at time.Time) (*models.SessionRevision, error) {
+	var (
+		historyStart *time.Time
+		version      *int
+		author       *string
+		createdAt    *time.Time
+		snapshot     *models.SessionSnapshot
+	)
+	err := c.Pool.QueryRow(ctx, `
+		SELECT s.history_start, r.version, r.author, r.created_at, r.snapshot
+		FROM sessions s
+		LEFT JOIN LATERAL (
+			SELECT version, author, created_at, snapshot
+			FROM session_revisions
+			WHERE session_id = s.id AND created_at <= $2
+			ORDER BY version DESC
+			LIMIT 1
+		) r ON true
+		WHERE s.id = $1 AND `+liveSession+`
+	`, sessionID, at).Scan(&historyStart, &version, &author, &createdAt, &snapshot)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query base revision: %w", err)
+	}
+
+	if version == nil {
+		if historyStart != nil && at.Before(*historyStart) {
+			return nil, ErrHistoryPruned
+		}
+		return nil, ErrRevisionNotFound
+	}
+
+	return &models.SessionRevision{
+		Version:   *version,
+		Author:    *author,
+		CreatedAt: *createdAt,
+		Snapshot:  snapshot,
+	}, nil
+}
+
+// PruneSessionRevisions deletes revisions beyond the newest keep, and those
+// older than maxAge. A zero keep or maxAge disables that limit. The newest
+// revision is never deleted.
+//
+// Events older than the oldest remaining revision go with them, since the
+// layout is rebuilt from that revision onwards, and history_start records
+// where the kept history begins.
+func (c *Client) PruneSessionRevisions(ctx context.Context, sessionID string, keep int, maxAge time.Duration) (int64, error) {
+	var cutoff *time.Time
+	if maxAge > 0 {
+		t := time.Now().Add(-maxAge)
+		cutoff = &t
+	}
+
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM session_revisions
+		WHERE session_id = $1
+			AND version < (SELECT MAX(version) FROM session_revisions WHERE session_id = $1)
+			AND (
+				($2 > 0 AND version NOT IN (
+					SELECT version FROM session_revisions
+					WHERE session_id = $1
+					ORDER BY version DESC
+					LIMIT $2
+				))
+				OR created_at < $3
+			)
+	`, sessionID, keep, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune revisions: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM session_events
+		WHERE session_id = $1
+			AND version < (SELECT MIN(version) FROM session_revisions WHERE session_id = $1)
+	`, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune session events: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE sessions
+		SET history_start = (SELECT MIN(created_at) FROM session_revisions WHERE session_id = $1)
+		WHERE id = $1
+	`, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record history start: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"server/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionSort is the order ListSessions returns sessions in.
+type SessionSort string
+
+const (
+	SortUpdated SessionSort = "updated"
+	SortCreated SessionSort = "created"
+	SortTitle   SessionSort = "title"
+)
+
+// sessionSorts maps each sort to its column, direction and the comparison
+// that selects rows after a cursor. The id breaks ties so the order is total.
+var sessionSorts = map[SessionSort]struct {
+	column, direction, after string
+}{
+	SortUpdated: {"updated_at", "DESC", "<"},
+	SortCreated: {"created_at", "DESC", "<"},
+	SortTitle:   {"title", "ASC", ">"},
+}
+
+// SessionCursor is the position of the last session of a page. Time is used
+// by the timestamp sorts and Title by SortTitle.
+type SessionCursor struct {
+	Time  time.Time
+	Title string
+	Id    string
+}
+
+// SessionQuery filters and orders the sessions of an owner or, if
+// WorkspaceID is set, of a workspace. Other zero fields do not filter.
+type SessionQuery struct {
+	OwnerID     string
+	WorkspaceID string
+	Text        string
+	DeviceID    string
+	Tags        []string
+	Sort        SessionSort
+	After       *SessionCursor
+	Limit       int
+}
+
+// ListSessions returns up to query.Limit live sessions of the owner or
+// workspace. Text matches session titles and descriptions or the titles of
+// their stickers.
+func (c *Client) ListSessions(ctx context.Context, query SessionQuery) ([]models.Session, error) {
+	sort, ok := sessionSorts[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown session sort %q", query.Sort)
+	}
+
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{liveSession}
+	if query.WorkspaceID != "" {
+		conditions = append(conditions, "workspace_id = "+arg(query.WorkspaceID))
+	} else {
+		conditions = append(conditions, "owner_id = "+arg(query.OwnerID))
+	}
+
+	if query.Text != "" {
+		tsquery := "websearch_to_tsquery('english', " + arg(query.Text) + ")"
+		conditions = append(conditions, `(
+			to_tsvector('english', title || ' ' || description) @@ `+tsquery+`
+			OR EXISTS (
+				SELECT 1 FROM session_stickers st
+				WHERE st.session_id = sessions.id AND to_tsvector('english', st.title) @@ `+tsquery+`
+			)
+		)`)
+	}
+	if query.DeviceID != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM session_surfaces su
+			WHERE su.session_id = sessions.id AND su.device_id = `+arg(query.DeviceID)+`
+		)`)
+	}
+	if len(query.Tags) > 0 {
+		conditions = append(conditions, "tags @> "+arg(query.Tags)+"::text[]")
+	}
+	if query.After != nil {
+		var value any = query.After.Time
+		if query.Sort == SortTitle {
+			value = query.After.Title
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)",
+			sort.column, sort.after, arg(value), arg(query.After.Id)))
+	}
+
+	sql := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY ` + sort.column + ` ` + sort.direction + `, id ` + sort.direction + `
+		LIMIT ` + arg(query.Limit)
+
+	return c.querySessions(ctx, sql, args...)
+}
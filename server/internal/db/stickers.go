@@ -0,0 +1,522 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSurfaceNotFound = errors.New("surface not found")
+	ErrStickerNotFound = errors.New("sticker not found")
+	ErrStickerExists   = errors.New("sticker already exists")
+	ErrSurfaceFull     = errors.New("surface has the most stickers allowed")
+)
+
+// MaxStickersPerSurface bounds the stickers of one surface, which coverage is
+// computed over on every request.
+const MaxStickersPerSurface = 200
+
+// bumpSessionVersion increments the version of the session inside tx, which
+// also locks its row until the transaction ends. Unless expectedVersion is 0
+// it fails with ErrVersionConflict if the stored version differs.
+func bumpSessionVersion(ctx context.Context, tx pgx.Tx, sessionID string, expectedVersion int) (int, error) {
+	var version int
+	err := tx.QueryRow(ctx, `
+		UPDATE sessions SET
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+		RETURNING version
+	`, sessionID, expectedVersion).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, sessionUpdateError(ctx, tx, sessionID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update session version: %w", err)
+	}
+	return version, nil
+}
+
+// sessionUpdateError explains why a conditional update of the session matched
+// no rows: either it does not exist or its version moved on.
+func sessionUpdateError(ctx context.Context, tx pgx.Tx, sessionID string) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1)", sessionID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrSessionNotFound
+}
+
+// querier is implemented by both the pool and transactions.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// resolveSurface returns surfaceID if the session has such a surface, or the
+// session's first surface when surfaceID is empty.
+func resolveSurface(ctx context.Context, q querier, sessionID, surfaceID string) (string, error) {
+	var resolved string
+	err := q.QueryRow(ctx, `
+		SELECT surface_id
+		FROM session_surfaces
+		WHERE session_id = $1 AND ($2 = '' OR surface_id = $2)
+		ORDER BY position
+		LIMIT 1
+	`, sessionID, surfaceID).Scan(&resolved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrSurfaceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query surface: %w", err)
+	}
+	return resolved, nil
+}
+
+// PatchSession applies ops in order inside one transaction and returns the new
+// session version. Only the placements an operation touches are written. Any
+// failing operation rolls back the whole patch.
+func (c *Client) PatchSession(ctx context.Context, sessionID string, ops []models.SessionPatchOperation, author string, expectedVersion int) (int, error) {
+	return c.updateStickers(ctx, sessionID, author, expectedVersion, func(tx pgx.Tx, version int) error {
+		for i, op := range ops {
+			surfaceID, err := resolveSurface(ctx, tx, sessionID, op.SurfaceId)
+			if err != nil {
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+
+			event := models.SessionEvent{SurfaceId: surfaceID, StickerId: op.StickerId}
+			switch op.Op {
+			case models.PatchOpAdd:
+				event.Type = models.SessionEventStickerAdded
+				event.Data.Sticker = op.Sticker
+				err = insertSticker(ctx, tx, sessionID, surfaceID, op.Sticker)
+			case models.PatchOpMove:
+				event.Type = models.SessionEventStickerMoved
+				event.Data.Position = op.Position
+				err = moveSticker(ctx, tx, sessionID, surfaceID, op.StickerId, *op.Position)
+			case models.PatchOpTransform:
+				event.Type = models.SessionEventStickerTransformed
+				event.Data.Transform = op.Transform
+				err = transformSticker(ctx, tx, sessionID, surfaceID, op.StickerId, *op.Transform)
+			case models.PatchOpReorder:
+				var zIndex int
+				event.Type = models.SessionEventStickerReordered
+				event.Data.ZIndex = &zIndex
+				zIndex, err = reorderSticker(ctx, tx, sessionID, surfaceID, op.StickerId, *op.ZIndex)
+			case models.PatchOpRemove:
+				event.Type = models.SessionEventStickerRemoved
+				err = deleteSticker(ctx, tx, sessionID, surfaceID, op.StickerId)
+			default:
+				err = fmt.Errorf("unknown operation %q", op.Op)
+			}
+			if err != nil {
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+
+			if err := appendSessionEvent(ctx, tx, sessionID, version, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// insertSticker adds the placement on top of the other stickers of the
+// surface, or returns ErrSurfaceFull if it already has MaxStickersPerSurface.
+func insertSticker(ctx context.Context, tx pgx.Tx, sessionID, surfaceID string, sticker *models.SavedStickerData) error {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO session_stickers (
+			session_id, surface_id, sticker_id, url, width, height, x, y, source, z_index,
+			rotation, scale, flip_x, flip_y, opacity, title
+		)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, COUNT(*),
+			$10, $11, $12, $13, $14, $15
+		FROM session_stickers
+		WHERE session_id = $1 AND surface_id = $2
+		HAVING COUNT(*) < $16
+		RETURNING z_index
+	`,
+		sessionID,
+		surfaceID,
+		sticker.StickerId,
+		sticker.URL,
+		sticker.Size.Width,
+		sticker.Size.Height,
+		sticker.Position.X,
+		sticker.Position.Y,
+		sticker.Source,
+		sticker.Transform.Rotation,
+		sticker.Transform.Scale,
+		sticker.Transform.FlipX,
+		sticker.Transform.FlipY,
+		sticker.Transform.Opacity,
+		sticker.Title,
+		MaxStickersPerSurface,
+	).Scan(&sticker.ZIndex)
+	if isUniqueViolation(err) {
+		return ErrStickerExists
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSurfaceFull
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert sticker: %w", err)
+	}
+	return nil
+}
+
+func moveSticker(ctx context.Context, tx pgx.Tx, sessionID, surfaceID, stickerID string, position models.Position) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE session_stickers SET x = $4, y = $5
+		WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+	`, sessionID, surfaceID, stickerID, position.X, position.Y)
+	if err != nil {
+		return fmt.Errorf("failed to move sticker: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStickerNotFound
+	}
+	return nil
+}
+
+func transformSticker(ctx context.Context, tx pgx.Tx, sessionID, surfaceID, stickerID string, t models.Transform) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE session_stickers SET rotation = $4, scale = $5, flip_x = $6, flip_y = $7, opacity = $8
+		WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+	`, sessionID, surfaceID, stickerID, t.Rotation, t.Scale, t.FlipX, t.FlipY, t.Opacity)
+	if err != nil {
+		return fmt.Errorf("failed to transform sticker: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStickerNotFound
+	}
+	return nil
+}
+
+// reorderSticker moves the sticker to zIndex, clamped to the stickers on the
+// surface, and shifts only the stickers between its old and new layer so
+// z-indices stay 0..n-1. It returns the layer the sticker ended up on.
+func reorderSticker(ctx context.Context, tx pgx.Tx, sessionID, surfaceID, stickerID string, zIndex int) (int, error) {
+	var current, count int
+	err := tx.QueryRow(ctx, `
+		SELECT z_index, (SELECT COUNT(*) FROM session_stickers WHERE session_id = $1 AND surface_id = $2)
+		FROM session_stickers
+		WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+	`, sessionID, surfaceID, stickerID).Scan(&current, &count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrStickerNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query sticker: %w", err)
+	}
+
+	zIndex = max(0, min(zIndex, count-1))
+	if zIndex == current {
+		return zIndex, nil
+	}
+
+	shift := `
+		UPDATE session_stickers SET z_index = z_index - 1
+		WHERE session_id = $1 AND surface_id = $2 AND z_index > $3 AND z_index <= $4
+	`
+	lo, hi := current, zIndex
+	if zIndex < current {
+		shift = `
+			UPDATE session_stickers SET z_index = z_index + 1
+			WHERE session_id = $1 AND surface_id = $2 AND z_index >= $3 AND z_index < $4
+		`
+		lo, hi = zIndex, current
+	}
+	if _, err := tx.Exec(ctx, shift, sessionID, surfaceID, lo, hi); err != nil {
+		return 0, fmt.Errorf("failed to shift stickers: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE session_stickers SET z_index = $4
+		WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+	`, sessionID, surfaceID, stickerID, zIndex)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reorder sticker: %w", err)
+	}
+	return zIndex, nil
+}
+
+// deleteSticker removes the sticker and closes the gap it leaves in the
+// z-indices of the surface.
+func deleteSticker(ctx context.Context, tx pgx.Tx, sessionID, surfaceID, stickerID string) error {
+	var zIndex int
+	err := tx.QueryRow(ctx, `
+		DELETE FROM session_stickers
+		WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+		RETURNING z_index
+	`, sessionID, surfaceID, stickerID).Scan(&zIndex)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrStickerNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete sticker: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE session_stickers SET z_index = z_index - 1
+		WHERE session_id = $1 AND surface_id = $2 AND z_index > $3
+	`, sessionID, surfaceID, zIndex)
+	if err != nil {
+		return fmt.Errorf("failed to shift stickers: %w", err)
+	}
+	return nil
+}
+
+// ListSessionStickers returns the placements of one surface of the session,
+// or of all surfaces when surfaceID is empty, bottom layer first.
+func (c *Client) ListSessionStickers(ctx context.Context, sessionID, surfaceID string) ([]models.PlacedSticker, error) {
+	if _, err := c.GetSessionMetadata(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	if surfaceID != "" {
+		if _, err := resolveSurface(ctx, c.Pool, sessionID, surfaceID); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := c.Pool.Query(ctx, `
+		SELECT st.surface_id, st.sticker_id, st.url, st.width, st.height, st.x, st.y, st.source, st.z_index,
+			st.rotation, st.scale, st.flip_x, st.flip_y, st.opacity, st.title
+		FROM session_stickers st
+		JOIN session_surfaces su ON su.session_id = st.session_id AND su.surface_id = st.surface_id
+		WHERE st.session_id = $1 AND ($2 = '' OR st.surface_id = $2)
+		ORDER BY su.position, st.z_index, st.id
+	`, sessionID, surfaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stickers: %w", err)
+	}
+	defer rows.Close()
+
+	stickers := []models.PlacedSticker{}
+	for rows.Next() {
+		sticker, err := scanPlacedSticker(rows)
+		if err != nil {
+			return nil, err
+		}
+		stickers = append(stickers, *sticker)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return stickers, nil
+}
+
+// GetSessionSticker returns a single placement. An empty surfaceID means the
+// session's first surface.
+func (c *Client) GetSessionSticker(ctx context.Context, sessionID, surfaceID, stickerID string) (*models.PlacedSticker, error) {
+	if _, err := c.GetSessionMetadata(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	surfaceID, err := resolveSurface(ctx, c.Pool, sessionID, surfaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	row := c.Pool.QueryRow(ctx, `
+		SELECT surface_id, sticker_id, url, width, height, x, y, source, z_index,
+			rotation, scale, flip_x, flip_y, opacity, title
+		FROM session_stickers
+		WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+	`, sessionID, surfaceID, stickerID)
+	sticker, err := scanPlacedSticker(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStickerNotFound
+	}
+	return sticker, err
+}
+
+func scanPlacedSticker(row pgx.Row) (*models.PlacedSticker, error) {
+	var sticker models.PlacedSticker
+	err := row.Scan(
+		&sticker.SurfaceId,
+		&sticker.StickerId,
+		&sticker.URL,
+		&sticker.Size.Width,
+		&sticker.Size.Height,
+		&sticker.Position.X,
+		&sticker.Position.Y,
+		&sticker.Source,
+		&sticker.ZIndex,
+		&sticker.Transform.Rotation,
+		&sticker.Transform.Scale,
+		&sticker.Transform.FlipX,
+		&sticker.Transform.FlipY,
+		&sticker.Transform.Opacity,
+		&sticker.Title,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+	return &sticker, nil
+}
+
+// AddSessionSticker places the sticker on top of its surface, filling in
+// sticker.SurfaceId and sticker.ZIndex, and returns the new session version.
+// It returns ErrStickerExists if the surface already has a sticker with that
+// id.
+func (c *Client) AddSessionSticker(ctx context.Context, sessionID string, sticker *models.PlacedSticker, author string, expectedVersion int) (int, error) {
+	return c.updateStickers(ctx, sessionID, author, expectedVersion, func(tx pgx.Tx, version int) error {
+		surfaceID, err := resolveSurface(ctx, tx, sessionID, sticker.SurfaceId)
+		if err != nil {
+			return err
+		}
+		sticker.SurfaceId = surfaceID
+		if err := insertSticker(ctx, tx, sessionID, surfaceID, &sticker.SavedStickerData); err != nil {
+			return err
+		}
+
+		return appendSessionEvent(ctx, tx, sessionID, version, models.SessionEvent{
+			Type:      models.SessionEventStickerAdded,
+			SurfaceId: surfaceID,
+			StickerId: sticker.StickerId,
+			Data:      models.SessionEventData{Sticker: &sticker.SavedStickerData},
+		})
+	})
+}
+
+// UpdateSessionSticker replaces every field of an existing placement,
+// including its layer, and returns the new session version.
+func (c *Client) UpdateSessionSticker(ctx context.Context, sessionID string, sticker *models.PlacedSticker, author string, expectedVersion int) (int, error) {
+	return c.updateStickers(ctx, sessionID, author, expectedVersion, func(tx pgx.Tx, version int) error {
+		surfaceID, err := resolveSurface(ctx, tx, sessionID, sticker.SurfaceId)
+		if err != nil {
+			return err
+		}
+		sticker.SurfaceId = surfaceID
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE session_stickers SET
+				url = $4, width = $5, height = $6, x = $7, y = $8, source = $9,
+				rotation = $10, scale = $11, flip_x = $12, flip_y = $13, opacity = $14, title = $15
+			WHERE session_id = $1 AND surface_id = $2 AND sticker_id = $3
+		`,
+			sessionID,
+			surfaceID,
+			sticker.StickerId,
+			sticker.URL,
+			sticker.Size.Width,
+			sticker.Size.Height,
+			sticker.Position.X,
+			sticker.Position.Y,
+			sticker.Source,
+			sticker.Transform.Rotation,
+			sticker.Transform.Scale,
+			sticker.Transform.FlipX,
+			sticker.Transform.FlipY,
+			sticker.Transform.Opacity,
+			sticker.Title,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update sticker: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrStickerNotFound
+		}
+
+		sticker.ZIndex, err = reorderSticker(ctx, tx, sessionID, surfaceID, sticker.StickerId, sticker.ZIndex)
+		if err != nil {
+			return err
+		}
+
+		return appendSessionEvent(ctx, tx, sessionID, version, models.SessionEvent{
+			Type:      models.SessionEventStickerUpdated,
+			SurfaceId: surfaceID,
+			StickerId: sticker.StickerId,
+			Data:      models.SessionEventData{Sticker: &sticker.SavedStickerData},
+		})
+	})
+}
+
+// DeleteSessionSticker removes a placement and returns the new session
+// version.
+func (c *Client) DeleteSessionSticker(ctx context.Context, sessionID, surfaceID, stickerID, author string, expectedVersion int) (int, error) {
+	return c.updateStickers(ctx, sessionID, author, expectedVersion, func(tx pgx.Tx, version int) error {
+		surfaceID, err := resolveSurface(ctx, tx, sessionID, surfaceID)
+		if err != nil {
+			return err
+		}
+		if err := deleteSticker(ctx, tx, sessionID, surfaceID, stickerID); err != nil {
+			return err
+		}
+
+		return appendSessionEvent(ctx, tx, sessionID, version, models.SessionEvent{
+			Type:      models.SessionEventStickerRemoved,
+			SurfaceId: surfaceID,
+			StickerId: stickerID,
+		})
+	})
+}
+
+// updateStickers runs fn in a transaction after bumping the session version,
+// passing it the new version to record events under. The resulting layout is
+// recorded as a revision by author in the same transaction.
+func (c *Client) updateStickers(ctx context.Context, sessionID, author string, expectedVersion int, fn func(tx pgx.Tx, version int) error) (int, error) {
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	version, err := bumpSessionVersion(ctx, tx, sessionID, expectedVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := fn(tx, version); err != nil {
+		return 0, err
+	}
+
+	if err := recordRevision(ctx, tx, sessionID, version, author); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return version, nil
+}
+
+// recordRevision snapshots the session as it stands inside tx and stores it
+// as the revision for version.
+func recordRevision(ctx context.Context, tx pgx.Tx, sessionID string, version int, author string) error {
+	var snapshot models.SessionSnapshot
+	err := tx.QueryRow(ctx, `
+		SELECT title, description, settings
+		FROM sessions
+		WHERE id = $1
+	`, sessionID).Scan(&snapshot.Title, &snapshot.Description, &snapshot.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to load session for revision: %w", err)
+	}
+
+	if snapshot.Surfaces, err = loadSurfaces(ctx, tx, sessionID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO session_revisions (session_id, version, author, snapshot)
+		VALUES ($1, $2, $3, $4)
+	`, sessionID, version, author, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrEmailTaken           = errors.New("email is already registered")
+	ErrLoginSessionNotFound = errors.New("login session not found or expired")
+)
+
+// CreateUser inserts a user with the given password hash. It returns
+// ErrEmailTaken if the email is already registered.
+func (c *Client) CreateUser(ctx context.Context, user *models.User, passwordHash []byte) error {
+	err := c.Pool.QueryRow(ctx, `
+		INSERT INTO users (id, email, display_name, password_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`, user.Id, user.Email, user.DisplayName, passwordHash).Scan(&user.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUserByEmail returns the user registered with email together with their
+// password hash.
+func (c *Client) GetUserByEmail(ctx context.Context, email string) (*models.User, []byte, error) {
+	var user models.User
+	var passwordHash []byte
+	err := c.Pool.QueryRow(ctx, `
+		SELECT id, email, display_name, created_at, password_hash
+		FROM users
+		WHERE email = $1
+	`, email).Scan(&user.Id, &user.Email, &user.DisplayName, &user.CreatedAt, &passwordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query user: %w", err)
+	}
+	return &user, passwordHash, nil
+}
+
+// CreateLoginSession stores a login session for the user and drops the
+// user's expired ones.
+func (c *Client) CreateLoginSession(ctx context.Context, tokenHash []byte, userID string, expiresAt time.Time) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM login_sessions WHERE user_id = $1 AND expires_at <= CURRENT_TIMESTAMP`, userID)
+	batch.Queue(`
+		INSERT INTO login_sessions (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, tokenHash, userID, expiresAt)
+
+	if err := c.Pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to create login session: %w", err)
+	}
+	return nil
+}
+
+// GetLoginSessionUser returns the user logged in with the token hash. It
+// returns ErrLoginSessionNotFound if there is no such login or it expired.
+func (c *Client) GetLoginSessionUser(ctx context.Context, tokenHash []byte) (*models.User, error) {
+	var user models.User
+	err := c.Pool.QueryRow(ctx, `
+		SELECT u.id, u.email, u.display_name, u.created_at
+		FROM login_sessions ls
+		JOIN users u ON u.id = ls.user_id
+		WHERE ls.token_hash = $1 AND ls.expires_at > CURRENT_TIMESTAMP
+	`, tokenHash).Scan(&user.Id, &user.Email, &user.DisplayName, &user.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLoginSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query login session: %w", err)
+	}
+	return &user, nil
+}
+
+// DeleteLoginSession ends a login. Unknown tokens are ignored.
+func (c *Client) DeleteLoginSession(ctx context.Context, tokenHash []byte) error {
+	if _, err := c.Pool.Exec(ctx, `DELETE FROM login_sessions WHERE token_hash = $1`, tokenHash); err != nil {
+		return fmt.Errorf("failed to delete login session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrMemberNotFound    = errors.New("workspace member not found")
+	ErrMemberExists      = errors.New("user is already a member")
+	ErrLastOwner         = errors.New("workspace would be left without an owner")
+)
+
+// CreateWorkspace inserts a workspace with ownerID as its first owner.
+func (c *Client) CreateWorkspace(ctx context.Context, workspace *models.Workspace, ownerID string) error {
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (id, name)
+		VALUES ($1, $2)
+		RETURNING created_at
+	`, workspace.Id, workspace.Name).Scan(&workspace.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, workspace.Id, ownerID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return fmt.Errorf("failed to add workspace owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	workspace.Role = models.WorkspaceRoleOwner
+	return nil
+}
+
+// ListUserWorkspaces returns the workspaces the user is a member of, with
+// their role in each.
+func (c *Client) ListUserWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error) {
+	rows, err := c.Pool.Query(ctx, `
+		SELECT w.id, w.name, wm.role, w.created_at
+		FROM workspace_members wm
+		JOIN workspaces w ON w.id = wm.workspace_id
+		WHERE wm.user_id = $1
+		ORDER BY w.name, w.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := []models.Workspace{}
+	for rows.Next() {
+		var workspace models.Workspace
+		if err := rows.Scan(&workspace.Id, &workspace.Name, &workspace.Role, &workspace.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+// GetWorkspace returns a workspace with the user's role in it. It returns
+// ErrWorkspaceNotFound if the user is not a member, so outsiders cannot tell
+// which workspaces exist.
+func (c *Client) GetWorkspace(ctx context.Context, workspaceID, userID string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := c.Pool.QueryRow(ctx, `
+		SELECT w.id, w.name, wm.role, w.created_at
+		FROM workspaces w
+		JOIN workspace_members wm ON wm.workspace_id = w.id AND wm.user_id = $2
+		WHERE w.id = $1
+	`, workspaceID, userID).Scan(&workspace.Id, &workspace.Name, &workspace.Role, &workspace.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace: %w", err)
+	}
+	return &workspace, nil
+}
+
+// DeleteWorkspace removes a workspace and its memberships. Its sessions stay
+// with their owners and tokens.
+func (c *Client) DeleteWorkspace(ctx context.Context, workspaceID string) error {
+	tag, err := c.Pool.Exec(ctx, `DELETE FROM workspaces WHERE id = $1`, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWorkspaceNotFound
+	}
+	return nil
+}
+
+// ListWorkspaceMembers returns the members of a workspace, owners first.
+func (c *Client) ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	rows, err := c.Pool.Query(ctx, `
+		SELECT u.id, u.email, u.display_name, wm.role, wm.created_at
+		FROM workspace_members wm
+		JOIN users u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1
+		ORDER BY CASE wm.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, u.email
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.WorkspaceMember{}
+	for rows.Next() {
+		var member models.WorkspaceMember
+		err := rows.Scan(&member.UserId, &member.Email, &member.DisplayName, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return members, nil
+}
+
+// GetWorkspaceRole returns the user's role in the workspace, or "" if they
+// are not a member.
+func (c *Client) GetWorkspaceRole(ctx context.Context, workspaceID, userID string) (string, error) {
+	var role string
+	err := c.Pool.QueryRow(ctx, `
+		SELECT role FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query workspace role: %w", err)
+	}
+	return role, nil
+}
+
+// AddWorkspaceMember adds a user with the given role. It returns
+// ErrMemberExists if they already are a member.
+func (c *Client) AddWorkspaceMember(ctx context.Context, workspaceID, userID, role string) error {
+	_, err := c.Pool.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, workspaceID, userID, role)
+	if isUniqueViolation(err) {
+		return ErrMemberExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add workspace member: %w", err)
+	}
+	return nil
+}
+
+// SetWorkspaceMemberRole changes the role of a member. It returns
+// ErrLastOwner if that would demote the only owner.
+func (c *Client) SetWorkspaceMemberRole(ctx context.Context, workspaceID, userID, role string) error {
+	return c.updateMembers(ctx, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE workspace_members SET role = $3
+			WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID, role)
+		if err != nil {
+			return fmt.Errorf("failed to update workspace member: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrMemberNotFound
+		}
+		return nil
+	})
+}
+
+// RemoveWorkspaceMember removes a member. It returns ErrLastOwner if they are
+// the only owner.
+func (c *Client) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	return c.updateMembers(ctx, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM workspace_members
+			WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to remove workspace member: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrMemberNotFound
+		}
+		return nil
+	})
+}
+
+// updateMembers runs fn with the workspace locked and commits only if the
+// workspace still has an owner afterwards.
+func (c *Client) updateMembers(ctx context.Context, workspaceID string, fn func(tx pgx.Tx) error) error {
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent changes queue up here, so two owners cannot demote each
+	// other at the same time.
+	var id string
+	err = tx.QueryRow(ctx, `SELECT id FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock workspace: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	var hasOwner bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND role = $2)
+	`, workspaceID, models.WorkspaceRoleOwner).Scan(&hasOwner)
+	if err != nil {
+		return fmt.Errorf("failed to check workspace owners: %w", err)
+	}
+	if !hasOwner {
+		return ErrLastOwner
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SetSessionWorkspace moves a session into a workspace, or out of any when
+// workspaceID is empty.
+func (c *Client) SetSessionWorkspace(ctx context.Context, sessionID, workspaceID string) error {
+	tag, err := c.Pool.Exec(ctx, `
+		UPDATE sessions SET workspace_id = NULLIF($2, '')
+		WHERE id = $1 AND `+liveSession+`
+	`, sessionID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to move session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
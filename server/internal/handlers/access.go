@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"server/internal/services"
+)
+
+// ClaimSession issues tokens for a session created before tokens existed.
+func (h *SessionHandler) ClaimSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokens, err := h.sessionService.ClaimSession(req.Context(), req.PathValue("id"))
+	if err != nil {
+		writeSessionError(w, err, "Failed to claim session")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// SessionToken serves /sessions/{id}/tokens/{kind}: POST regenerates the view
+// or edit token and DELETE revokes the view token. Both need edit access and,
+// for owned sessions, the owner of the session or of its workspace.
+func (h *SessionHandler) SessionToken(w http.ResponseWriter, req *http.Request) {
+	sessionId := req.PathValue("id")
+	kind := req.PathValue("kind")
+
+	userId, err := h.currentUserId(req)
+	if err != nil {
+		writeSessionError(w, err, "Failed to check login")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		tokens, err := h.sessionService.RegenerateToken(req.Context(), sessionId, kind, userId)
+		if err != nil {
+			writeSessionError(w, err, "Failed to regenerate token")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tokens)
+
+	case http.MethodDelete:
+		if kind != services.TokenKindView {
+			http.Error(w, "Only the view token can be revoked; regenerate the edit token instead", http.StatusBadRequest)
+			return
+		}
+		if err := h.sessionService.RevokeViewToken(req.Context(), sessionId, userId); err != nil {
+			writeSessionError(w, err, "Failed to revoke token")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
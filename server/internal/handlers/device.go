@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"server/internal/services"
+)
+
+type DeviceHandler struct {
+	deviceService *services.DeviceService
+}
+
+func NewDeviceHandler(deviceService *services.DeviceService) *DeviceHandler {
+	return &DeviceHandler{
+		deviceService: deviceService,
+	}
+}
+
+func (h *DeviceHandler) ListDevices(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	devices, err := h.deviceService.ListDevices(req.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch devices", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(devices)
+}
+
+func (h *DeviceHandler) GetDevice(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	device, err := h.deviceService.GetDevice(req.Context(), req.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch device", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(device)
+}
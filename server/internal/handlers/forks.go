@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"server/internal/models"
+)
+
+// ForkSession creates a copy of the session, which only needs view access,
+// and returns the fork with its own tokens. The body is optional.
+func (h *SessionHandler) ForkSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.ForkSessionRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	user, err := currentUser(req, h.userService)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fork session")
+		return
+	}
+	var ownerId string
+	if user != nil {
+		ownerId, dat.Author = user.Id, user.DisplayName
+	}
+
+	fork, err := h.sessionService.ForkSession(req.Context(), sessionId, &dat, ownerId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fork session")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(fork.Version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(fork)
+}
+
+// GetLineage lists the ancestors and forks of a session. Those the caller
+// cannot view are reduced to their ids or counted.
+func (h *SessionHandler) GetLineage(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	creds, err := h.credentials(req, sessionId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to check login")
+		return
+	}
+
+	lineage, err := h.sessionService.GetLineage(req.Context(), sessionId, creds)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fetch session lineage")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lineage)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"server/internal/services"
+)
+
+type ImageHandler struct {
+	imageService *services.ImageService
+}
+
+func NewImageHandler(imageService *services.ImageService) *ImageHandler {
+	return &ImageHandler{
+		imageService: imageService,
+	}
+}
+
+func (h *ImageHandler) GetImage(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := req.PathValue("hash")
+
+	width := 0
+	if wStr := req.URL.Query().Get("w"); wStr != "" {
+		var err error
+		width, err = strconv.Atoi(wStr)
+		if err != nil {
+			http.Error(w, "Invalid w parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	contentType, data, err := h.imageService.GetImage(req.Context(), hash, width)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidImageWidth):
+			http.Error(w, "Width not allowed", http.StatusBadRequest)
+		case errors.Is(err, services.ErrImageNotFound):
+			http.Error(w, "Image not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to fetch image", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Images are addressed by content hash, so they never change.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"server/internal/services"
+)
+
+// metricsToken is the bearer token METRICS_TOKEN that /metrics/janitor
+// requires. It is read on first use so values from .env apply.
+var metricsToken = sync.OnceValue(func() string {
+	return os.Getenv("METRICS_TOKEN")
+})
+
+type JanitorHandler struct {
+	janitor *services.SessionJanitor
+}
+
+func NewJanitorHandler(janitor *services.SessionJanitor) *JanitorHandler {
+	return &JanitorHandler{janitor: janitor}
+}
+
+// Metrics serves the janitor's counters to callers that send METRICS_TOKEN
+// as a bearer token. Without METRICS_TOKEN set the endpoint does not exist,
+// and without a database there is no janitor to report on.
+func (h *JanitorHandler) Metrics(w http.ResponseWriter, req *http.Request) {
+	token := metricsToken()
+	if token == "" || h.janitor == nil {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sent, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "A valid metrics token is required", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.janitor.Stats())
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"server/internal/models"
+	"server/internal/services"
+)
+
+type LibraryHandler struct {
+	libraryService *services.LibraryService
+	userService    *services.UserService
+}
+
+func NewLibraryHandler(libraryService *services.LibraryService, userService *services.UserService) *LibraryHandler {
+	return &LibraryHandler{
+		libraryService: libraryService,
+		userService:    userService,
+	}
+}
+
+// writeLibraryError maps library service errors to HTTP responses, falling
+// back to a 500 with the given message.
+func writeLibraryError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrLibraryItemNotFound):
+		http.Error(w, "Library item not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidLibraryItem):
+		http.Error(w, "Library items need an http(s) or /images URL and a positive size", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidNotes):
+		http.Error(w, "Notes are too long", http.StatusBadRequest)
+	default:
+		writeSessionError(w, err, fallback)
+	}
+}
+
+// parseLibraryItemId reads the item id from the path. Malformed ids cannot
+// name an item, so they are reported as not found.
+func parseLibraryItemId(w http.ResponseWriter, req *http.Request) (int64, bool) {
+	itemId, err := strconv.ParseInt(req.PathValue("itemId"), 10, 64)
+	if err != nil || itemId < 1 {
+		writeLibraryError(w, services.ErrLibraryItemNotFound, "")
+		return 0, false
+	}
+	return itemId, true
+}
+
+// Library serves /library: GET lists the caller's personal library, or with
+// ?workspace= that of a workspace, and POST saves a resolved sticker. The
+// list takes q, repeatable tag, cursor and limit like /sessions.
+func (h *LibraryHandler) Library(w http.ResponseWriter, req *http.Request) {
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		params := req.URL.Query()
+		listReq := models.ListLibraryRequest{
+			WorkspaceId: params.Get("workspace"),
+			Query:       params.Get("q"),
+			Tags:        params["tag"],
+			Cursor:      params.Get("cursor"),
+		}
+		if limit := params.Get("limit"); limit != "" {
+			var err error
+			if listReq.Limit, err = strconv.Atoi(limit); err != nil {
+				writeLibraryError(w, services.ErrInvalidLimit, "")
+				return
+			}
+		}
+
+		list, err := h.libraryService.ListItems(req.Context(), user.Id, &listReq)
+		if err != nil {
+			writeLibraryError(w, err, "Failed to list library")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var dat models.SaveLibraryItemRequest
+		if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+
+		item, err := h.libraryService.SaveItem(req.Context(), user.Id, &dat)
+		if err != nil {
+			writeLibraryError(w, err, "Failed to save library item")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/library/"+strconv.FormatInt(item.Id, 10))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(item)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LibraryItem serves GET, PUT and DELETE /library/{itemId}. PUT changes the
+// title, notes and tags; the image itself is fixed once saved.
+func (h *LibraryHandler) LibraryItem(w http.ResponseWriter, req *http.Request) {
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+	itemId, ok := parseLibraryItemId(w, req)
+	if !ok {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		item, err := h.libraryService.GetItem(req.Context(), user.Id, itemId)
+		if err != nil {
+			writeLibraryError(w, err, "Failed to fetch library item")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(item)
+
+	case http.MethodPut:
+		var dat models.UpdateLibraryItemRequest
+		if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+
+		item, err := h.libraryService.UpdateItem(req.Context(), user.Id, itemId, &dat)
+		if err != nil {
+			writeLibraryError(w, err, "Failed to update library item")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(item)
+
+	case http.MethodDelete:
+		if err := h.libraryService.DeleteItem(req.Context(), user.Id, itemId); err != nil {
+			writeLibraryError(w, err, "Failed to delete library item")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SessionLibraryStickers serves POST /sessions/{id}/library-stickers, which
+// places a copy of a library item on the session without resolving the
+// sticker again. It needs edit access to the session and a login that can
+// see the item, requires If-Match like POST /sessions/{id}/stickers and
+// returns the placement.
+func (h *SessionHandler) SessionLibraryStickers(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessionId := req.PathValue("id")
+
+	expectedVersion, ok := readIfMatch(w, req, true)
+	if !ok {
+		return
+	}
+
+	user, err := currentUser(req, h.userService)
+	if err != nil {
+		writeSessionError(w, err, "Failed to check login")
+		return
+	}
+	var userId, author string
+	if user != nil {
+		userId, author = user.Id, user.DisplayName
+	}
+
+	var dat models.PlaceLibraryItemRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sticker, version, err := h.sessionService.PlaceLibraryItem(req.Context(), sessionId, userId, author, &dat, expectedVersion)
+	if err != nil {
+		if errors.Is(err, services.ErrLibraryItemNotFound) {
+			writeLibraryError(w, err, "")
+			return
+		}
+		h.writeStickerWriteError(w, req, sessionId, err)
+		return
+	}
+
+	location := "/sessions/" + url.PathEscape(sessionId) + "/stickers/" + url.PathEscape(sticker.StickerId) +
+		"?surfaceId=" + url.QueryEscape(sticker.SurfaceId)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", location)
+	w.Header().Set("ETag", sessionETag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sticker)
+}
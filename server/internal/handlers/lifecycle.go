@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"server/internal/models"
+)
+
+// Session serves /sessions/{id}: PATCH edits placements and DELETE moves the
+// session to the trash.
+func (h *SessionHandler) Session(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPatch:
+		h.PatchSession(w, req)
+	case http.MethodDelete:
+		h.DeleteSession(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DeleteSession soft-deletes a session. It needs edit access and can be undone
+// with RestoreSession within the restore window.
+func (h *SessionHandler) DeleteSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	if err := h.sessionService.DeleteSession(req.Context(), sessionId); err != nil {
+		writeSessionError(w, err, "Failed to delete session")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreSession undoes a soft delete. It needs the credentials that gave edit
+// access before the session was deleted.
+func (h *SessionHandler) RestoreSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	creds, err := h.credentials(req, sessionId)
+	if err == nil {
+		err = h.sessionService.RestoreSession(req.Context(), sessionId, creds)
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to restore session")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SessionExpiry serves PUT /sessions/{id}/expiry, which sets or, with a null
+// expiresAt, removes the expiry of a session. It needs edit access.
+func (h *SessionHandler) SessionExpiry(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.SetExpiryRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	if err := h.sessionService.SetExpiry(req.Context(), sessionId, dat.ExpiresAt); err != nil {
+		writeSessionError(w, err, "Failed to set session expiry")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/models"
+)
+
+// trustForwardedFor reports whether TRUST_FORWARDED_FOR=true is set, as it
+// should be behind a proxy that appends the client address to
+// X-Forwarded-For, such as Cloud Run. It is read on first use so values from
+// .env apply.
+var trustForwardedFor = sync.OnceValue(func() bool {
+	return os.Getenv("TRUST_FORWARDED_FOR") == "true"
+})
+
+// clientIP returns the address failed logins and unlocks are counted
+// against. Behind a trusted proxy that is the last X-Forwarded-For entry;
+// earlier entries come from the client and cannot be trusted. Without one
+// the header is ignored, since clients could set it to dodge the limits.
+func clientIP(req *http.Request) string {
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" && trustForwardedFor() {
+		hops := strings.Split(forwarded, ",")
+		return strings.TrimSpace(hops[len(hops)-1])
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// SessionPasscode serves /sessions/{id}/passcode: PUT sets or changes the
+// passcode and DELETE removes it. Both need edit access, an unlock if the
+// session is already locked, and for owned sessions the owner of the session
+// or of its workspace.
+func (h *SessionHandler) SessionPasscode(w http.ResponseWriter, req *http.Request) {
+	sessionId := req.PathValue("id")
+
+	userId, err := h.currentUserId(req)
+	if err != nil {
+		writeSessionError(w, err, "Failed to check login")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		var dat models.SetPasscodeRequest
+		if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+		if err := h.sessionService.SetPasscode(req.Context(), sessionId, dat.Passcode, userId); err != nil {
+			writeSessionError(w, err, "Failed to set passcode")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if err := h.sessionService.RemovePasscode(req.Context(), sessionId, userId); err != nil {
+			writeSessionError(w, err, "Failed to remove passcode")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// UnlockSession exchanges the passcode for an unlock token, set as an
+// HttpOnly cookie and also returned for clients that send it as
+// X-Unlock-Token.
+func (h *SessionHandler) UnlockSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.UnlockSessionRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	creds, err := h.credentials(req, sessionId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to unlock session")
+		return
+	}
+
+	unlock, err := h.sessionService.Unlock(req.Context(), sessionId, creds, dat.Passcode, clientIP(req))
+	if err != nil {
+		writeSessionError(w, err, "Failed to unlock session")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookieName(sessionId),
+		Value:    unlock.UnlockToken,
+		Path:     "/",
+		Expires:  unlock.ExpiresAt,
+		MaxAge:   int(time.Until(unlock.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(unlock)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"server/internal/services"
+)
+
+type ProductHandler struct {
+	productService *services.ProductService
+}
+
+func NewProductHandler(productService *services.ProductService) *ProductHandler {
+	return &ProductHandler{
+		productService: productService,
+	}
+}
+
+func (h *ProductHandler) GetNearDuplicates(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	productID, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid product id", http.StatusBadRequest)
+		return
+	}
+
+	maxDistance := services.DefaultDuplicateDistance
+	if dStr := req.URL.Query().Get("maxDistance"); dStr != "" {
+		maxDistance, err = strconv.Atoi(dStr)
+		if err != nil || maxDistance < 0 || maxDistance > services.MaxDuplicateDistance {
+			http.Error(w, "Invalid maxDistance parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	duplicates, err := h.productService.FindNearDuplicates(req.Context(), productID, maxDistance)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductNotFound):
+			http.Error(w, "Product not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrProductNotHashed):
+			http.Error(w, "Product image has not been hashed", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to fetch near duplicates", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(duplicates)
+}
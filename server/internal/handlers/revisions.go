@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"server/internal/models"
+	"server/internal/services"
+)
+
+func (h *SessionHandler) ListRevisions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	revisions, err := h.sessionService.ListRevisions(req.Context(), sessionId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fetch revisions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// GetRevision returns a revision with its snapshot. Positions are in inches.
+func (h *SessionHandler) GetRevision(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, err := strconv.Atoi(req.PathValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid revision version", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	revision, err := h.sessionService.GetRevision(req.Context(), sessionId, version)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fetch revision")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revision)
+}
+
+// RestoreRevision makes an earlier revision the new head. Like any other
+// write it requires If-Match with the current session version.
+func (h *SessionHandler) RestoreRevision(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, err := strconv.Atoi(req.PathValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid revision version", http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, ok := readIfMatch(w, req, true)
+	if !ok {
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	author, ok := h.revisionAuthor(w, req)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.sessionService.RestoreRevision(req.Context(), sessionId, version, author, expectedVersion)
+	if errors.Is(err, services.ErrVersionConflict) {
+		h.writeVersionConflict(w, req, sessionId, false)
+		return
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to restore revision")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(newVersion))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.SaveSessionResponse{Status: "success", Version: newVersion})
+}
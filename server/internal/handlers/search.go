@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"server/internal/models"
+	"server/internal/services"
+)
+
+// Sessions serves /sessions: GET lists the caller's sessions and POST creates
+// one.
+func (h *SessionHandler) Sessions(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		h.ListSessions(w, req)
+	case http.MethodPost:
+		h.CreateSession(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ListSessions serves GET /sessions?owner=me&q=&device=&tag=&sort=&cursor=&limit=
+// for the logged-in user, or lists a workspace's sessions with ?workspace=
+// instead of owner. tag may be repeated; sessions must have all of them.
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	dat := models.ListSessionsRequest{
+		Owner:       query.Get("owner"),
+		WorkspaceId: query.Get("workspace"),
+		Query:       query.Get("q"),
+		DeviceId:    query.Get("device"),
+		Tags:        query["tag"],
+		Sort:        query.Get("sort"),
+		Cursor:      query.Get("cursor"),
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			writeSessionError(w, services.ErrInvalidLimit, "")
+			return
+		}
+		dat.Limit = limit
+	}
+
+	userId, err := h.currentUserId(req)
+	if err != nil {
+		writeSessionError(w, err, "Failed to list sessions")
+		return
+	}
+
+	list, err := h.sessionService.ListSessions(req.Context(), userId, &dat)
+	if err != nil {
+		writeSessionError(w, err, "Failed to list sessions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
+
+// SessionTags serves PUT /sessions/{id}/tags, which replaces the tags of a
+// session and returns them normalized. It needs edit access.
+func (h *SessionHandler) SessionTags(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.SetTagsRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	tags, err := h.sessionService.SetTags(req.Context(), sessionId, dat.Tags)
+	if err != nil {
+		writeSessionError(w, err, "Failed to set session tags")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.SetTagsRequest{Tags: tags})
+}
+
+// SessionWorkspace serves PUT /sessions/{id}/workspace, which moves the
+// session into a workspace, or out of it with an empty workspaceId. It needs
+// edit access to the session, to own it or its current workspace, and at
+// least the editor role in the target workspace.
+func (h *SessionHandler) SessionWorkspace(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.MoveSessionRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	userId, err := h.currentUserId(req)
+	if err == nil {
+		err = h.sessionService.MoveSession(req.Context(), sessionId, userId, dat.WorkspaceId)
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to move session")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"server/internal/models"
 	"server/internal/services"
@@ -10,38 +14,420 @@ import (
 
 type SessionHandler struct {
 	sessionService *services.SessionService
+	userService    *services.UserService
 }
 
-func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+func NewSessionHandler(sessionService *services.SessionService, userService *services.UserService) *SessionHandler {
 	return &SessionHandler{
 		sessionService: sessionService,
+		userService:    userService,
 	}
 }
 
+// writeSessionError maps session service errors to HTTP responses, falling
+// back to a 500 with the given message.
+func writeSessionError(w http.ResponseWriter, err error, fallback string) {
+	var retry *services.RetryError
+	switch {
+	case errors.As(err, &retry):
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.RetryAfter.Seconds())+1))
+		http.Error(w, "Too many failed attempts", http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrInvalidSessionId):
+		http.Error(w, "Malformed session id", http.StatusBadRequest)
+	case errors.Is(err, services.ErrSessionNotFound):
+		http.Error(w, "Session not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrSurfaceNotFound):
+		http.Error(w, "Surface not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidSessionTitle):
+		http.Error(w, "Session title is too long", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidStickerSource):
+		http.Error(w, "Invalid sticker source", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidTransform):
+		http.Error(w, "Invalid sticker transform", http.StatusBadRequest)
+	case errors.Is(err, services.ErrDeviceNotFound), errors.Is(err, services.ErrDeviceVariantNotFound):
+		http.Error(w, "Unknown device or device variant", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidSurface):
+		http.Error(w, "Surface ids must be unique and 1-64 characters", http.StatusBadRequest)
+	case errors.Is(err, services.ErrTooManySurfaces):
+		http.Error(w, "A session has at most 10 surfaces", http.StatusBadRequest)
+	case errors.Is(err, services.ErrTooManyStickers):
+		http.Error(w, "A surface has at most 200 stickers", http.StatusBadRequest)
+	case errors.Is(err, services.ErrDuplicateStickerId):
+		http.Error(w, "Sticker ids must be unique within a surface", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidPatch):
+		http.Error(w, "Invalid patch operation", http.StatusBadRequest)
+	case errors.Is(err, services.ErrStickerNotFound):
+		http.Error(w, "Sticker not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrAccessDenied):
+		http.Error(w, "A valid session token is required", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrEditAccessRequired):
+		http.Error(w, "This token only grants view access", http.StatusForbidden)
+	case errors.Is(err, services.ErrSessionAlreadyProtected):
+		http.Error(w, "Session already has tokens", http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidTokenKind):
+		http.Error(w, "Token kind must be view or edit", http.StatusBadRequest)
+	case errors.Is(err, services.ErrSessionLocked):
+		http.Error(w, "Session is locked; unlock it with its passcode", http.StatusLocked)
+	case errors.Is(err, services.ErrWrongPasscode):
+		http.Error(w, "Wrong passcode", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrInvalidPasscode):
+		http.Error(w, "Passcode must be 4-72 characters", http.StatusBadRequest)
+	case errors.Is(err, services.ErrNotLoggedIn):
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrInvalidOwner):
+		http.Error(w, "Owner must be me and cannot be combined with workspace", http.StatusBadRequest)
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrWorkspaceRoleRequired):
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+	case errors.Is(err, services.ErrSessionOwnerRequired):
+		http.Error(w, "Only the owner of the session or of its workspace can do this", http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidSort):
+		http.Error(w, "Sort must be updated, created or title", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidCursor):
+		http.Error(w, "Malformed cursor", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidLimit):
+		http.Error(w, "Limit must be 1-100", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidQuery):
+		http.Error(w, "Search query is too long", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidTags):
+		http.Error(w, "Tags must be 1-50 characters and at most 20", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidStickerTitle):
+		http.Error(w, "Sticker title is too long", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidExpiry):
+		http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+	case errors.Is(err, services.ErrSessionNotDeleted):
+		http.Error(w, "Session is not deleted", http.StatusConflict)
+	case errors.Is(err, services.ErrRestoreWindowExpired):
+		http.Error(w, "Session was deleted too long ago to restore", http.StatusGone)
+	case errors.Is(err, services.ErrNoPasscode):
+		http.Error(w, "Session has no passcode", http.StatusConflict)
+	case errors.Is(err, services.ErrSessionNotProtected):
+		http.Error(w, "Claim the session before setting a passcode", http.StatusConflict)
+	case errors.Is(err, services.ErrRevisionNotFound):
+		http.Error(w, "Revision not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrHistoryPruned):
+		http.Error(w, "History before this time is no longer kept", http.StatusGone)
+	case errors.Is(err, services.ErrInvalidStickerId):
+		http.Error(w, "Sticker id is required and must match the URL", http.StatusBadRequest)
+	case errors.Is(err, services.ErrStickerExists):
+		http.Error(w, "Sticker already exists", http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// sessionToken returns the session token from an "Authorization: Bearer"
+// header or, for share links, the token query parameter.
+func sessionToken(req *http.Request) string {
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return req.URL.Query().Get("token")
+}
+
+// unlockCookieName is the cookie holding the unlock token of a session.
+func unlockCookieName(sessionId string) string {
+	return "unlock_" + sessionId
+}
+
+// credentials collects the session token, the unlock token, which comes
+// from the X-Unlock-Token header or the session's unlock cookie, and the
+// logged-in user.
+func (h *SessionHandler) credentials(req *http.Request, sessionId string) (services.Credentials, error) {
+	creds := services.Credentials{
+		Token:       sessionToken(req),
+		UnlockToken: req.Header.Get("X-Unlock-Token"),
+	}
+	if creds.UnlockToken == "" {
+		if cookie, err := req.Cookie(unlockCookieName(sessionId)); err == nil {
+			creds.UnlockToken = cookie.Value
+		}
+	}
+
+	user, err := currentUser(req, h.userService)
+	if err != nil {
+		return creds, err
+	}
+	if user != nil {
+		creds.UserId = user.Id
+	}
+	return creds, nil
+}
+
+// currentUserId returns the id of the logged-in user, or "" if there is none.
+func (h *SessionHandler) currentUserId(req *http.Request) (string, error) {
+	user, err := currentUser(req, h.userService)
+	if err != nil || user == nil {
+		return "", err
+	}
+	return user.Id, nil
+}
+
+// revisionAuthor returns the name recorded on the revisions a write creates:
+// the display name of the logged-in user, or "" when the request is
+// authorized by a token alone. It writes the error response and returns false
+// if the login cannot be checked.
+func (h *SessionHandler) revisionAuthor(w http.ResponseWriter, req *http.Request) (string, bool) {
+	user, err := currentUser(req, h.userService)
+	if err != nil {
+		writeSessionError(w, err, "Failed to check login")
+		return "", false
+	}
+	if user == nil {
+		return "", true
+	}
+	return user.DisplayName, true
+}
+
+// authorize checks that the request's credentials grant the needed access to
+// the session, writing the error response and returning false if not.
+func (h *SessionHandler) authorize(w http.ResponseWriter, req *http.Request, sessionId string, need services.AccessLevel) bool {
+	creds, err := h.credentials(req, sessionId)
+	if err == nil {
+		err = h.sessionService.Authorize(req.Context(), sessionId, creds, need)
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to check session access")
+		return false
+	}
+	return true
+}
+
+// RequireAccess wraps the handler of a /sessions/{id}/... route so that it
+// only runs if the request's credentials grant access to the session named in
+// the path: view access for GET and HEAD and edit access for other methods.
+// Routes that name the session in the query or body call authorize instead.
+func (h *SessionHandler) RequireAccess(next http.HandlerFunc) http.HandlerFunc {
+	return h.requireAccess(services.AccessEdit, next)
+}
+
+// RequireViewAccess is RequireAccess for routes that only read the session
+// whatever their method, such as forking it.
+func (h *SessionHandler) RequireViewAccess(next http.HandlerFunc) http.HandlerFunc {
+	return h.requireAccess(services.AccessView, next)
+}
+
+func (h *SessionHandler) requireAccess(write services.AccessLevel, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		need := write
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			need = services.AccessView
+		}
+		if !h.authorize(w, req, req.PathValue("id"), need) {
+			return
+		}
+		next(w, req)
+	}
+}
+
+// errPreconditionRequired is returned by parseIfMatch when a save must be
+// conditional but the client sent no If-Match header, or "*".
+var errPreconditionRequired = errors.New("If-Match with the session version is required")
+
+// sessionETag formats a session version as a strong entity tag.
+func sessionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch returns the session version the client expects to overwrite.
+// Unless required is set, "*" and a missing header mean any version (0).
+func parseIfMatch(req *http.Request, required bool) (int, error) {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		if required {
+			return 0, errPreconditionRequired
+		}
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, errors.New("malformed If-Match header")
+	}
+	return version, nil
+}
+
+// readIfMatch is parseIfMatch for handlers: on a missing or malformed header
+// it writes the 428 or 400 response and returns false.
+func readIfMatch(w http.ResponseWriter, req *http.Request, required bool) (int, bool) {
+	version, err := parseIfMatch(req, required)
+	if errors.Is(err, errPreconditionRequired) {
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+	return version, true
+}
+
+func (h *SessionHandler) CreateSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The body is optional; an empty one creates an untitled session.
+	var dat models.CreateSessionRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	// Sessions created while logged in belong to the user.
+	ownerId, err := h.currentUserId(req)
+	if err != nil {
+		writeSessionError(w, err, "Failed to create session")
+		return
+	}
+
+	session, err := h.sessionService.CreateSession(req.Context(), &dat, ownerId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to create session")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(session.Version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// SaveSession is the v1 endpoint, which takes positions in client pixels.
+// Like v2 it requires If-Match with the ETag from the last get or save:
+// clients that predate versions get a 428 rather than overwriting newer
+// saves.
 func (h *SessionHandler) SaveSession(w http.ResponseWriter, req *http.Request) {
+	h.saveSession(w, req, true)
+}
+
+// SaveSessionV2 takes positions in inches from the top-left of the device
+// surface and requires If-Match with the ETag from the last get or save.
+func (h *SessionHandler) SaveSessionV2(w http.ResponseWriter, req *http.Request) {
+	h.saveSession(w, req, false)
+}
+
+func (h *SessionHandler) saveSession(w http.ResponseWriter, req *http.Request, legacyPixels bool) {
 	if req.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	expectedVersion, ok := readIfMatch(w, req, true)
+	if !ok {
+		return
+	}
+
 	var dat models.SaveSessionRequest
 	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
 		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
 		return
 	}
 
+	if !h.authorize(w, req, dat.SessionId, services.AccessEdit) {
+		return
+	}
+	author, ok := h.revisionAuthor(w, req)
+	if !ok {
+		return
+	}
+	dat.Author = author
+
+	if legacyPixels {
+		services.LegacyPixelsToInches(&dat)
+	}
+
 	// Call sessionService saveSession with context
-	if err := h.sessionService.SaveSession(req.Context(), &dat); err != nil {
-		http.Error(w, "Failed to save session data", http.StatusInternalServerError)
+	version, err := h.sessionService.SaveSession(req.Context(), &dat, expectedVersion)
+	if errors.Is(err, services.ErrVersionConflict) {
+		h.writeVersionConflict(w, req, dat.SessionId, legacyPixels)
+		return
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to save session data")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.SaveSessionResponse{Status: "success", Version: version})
+}
+
+// writeVersionConflict answers a stale save with 412 and the session as it is
+// now stored, so the client can merge or reload without another request.
+func (h *SessionHandler) writeVersionConflict(w http.ResponseWriter, req *http.Request, sessionId string, legacyPixels bool) {
+	current, err := h.sessionService.GetSession(req.Context(), sessionId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fetch session data")
+		return
+	}
+
+	if legacyPixels {
+		services.InchesToLegacyPixels(current)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(current.Session.Version))
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(current)
+}
+
+// PatchSession applies a list of placement operations and requires If-Match
+// like SaveSessionV2. Positions are in inches.
+func (h *SessionHandler) PatchSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	expectedVersion, ok := readIfMatch(w, req, true)
+	if !ok {
+		return
+	}
+
+	var dat models.PatchSessionRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	author, ok := h.revisionAuthor(w, req)
+	if !ok {
+		return
+	}
+
+	version, err := h.sessionService.PatchSession(req.Context(), sessionId, dat.Operations, author, expectedVersion)
+	if errors.Is(err, services.ErrVersionConflict) {
+		h.writeVersionConflict(w, req, sessionId, false)
+		return
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to patch session")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(version))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(models.SaveSessionResponse{Status: "success", Version: version})
 }
 
+// GetSession is the v1 endpoint, which returns positions in client pixels.
 func (h *SessionHandler) GetSession(w http.ResponseWriter, req *http.Request) {
+	h.getSession(w, req, true)
+}
+
+// GetSessionV2 returns positions in inches from the top-left of the device
+// surface.
+func (h *SessionHandler) GetSessionV2(w http.ResponseWriter, req *http.Request) {
+	h.getSession(w, req, false)
+}
+
+func (h *SessionHandler) getSession(w http.ResponseWriter, req *http.Request, legacyPixels bool) {
 	if req.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -54,14 +440,79 @@ func (h *SessionHandler) GetSession(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if !h.authorize(w, req, sessionId, services.AccessView) {
+		return
+	}
+
 	// Call sessionService getSession with context
 	sessionData, err := h.sessionService.GetSession(req.Context(), sessionId)
 	if err != nil {
-		http.Error(w, "Failed to fetch session data", http.StatusInternalServerError)
+		writeSessionError(w, err, "Failed to fetch session data")
 		return
 	}
 
+	if legacyPixels {
+		services.InchesToLegacyPixels(sessionData)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", sessionETag(sessionData.Session.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(sessionData)
 }
+
+func (h *SessionHandler) GetSessionPalette(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.URL.Query().Get("sessionId")
+	if sessionId == "" {
+		http.Error(w, "sessionId parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorize(w, req, sessionId, services.AccessView) {
+		return
+	}
+
+	palette, err := h.sessionService.GetSessionPalette(req.Context(), sessionId)
+	if err != nil {
+		http.Error(w, "Failed to fetch session palette", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(palette)
+}
+
+func (h *SessionHandler) GetSessionCoverage(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.URL.Query().Get("sessionId")
+	if sessionId == "" {
+		http.Error(w, "sessionId parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	surfaceId := req.URL.Query().Get("surfaceId")
+
+	if !h.authorize(w, req, sessionId, services.AccessView) {
+		return
+	}
+
+	coverage, err := h.sessionService.GetSessionCoverage(req.Context(), sessionId, surfaceId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to compute session coverage")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coverage)
+}
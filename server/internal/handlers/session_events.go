@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+func (h *SessionHandler) ListEvents(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionId := req.PathValue("id")
+	events, err := h.sessionService.ListEvents(req.Context(), sessionId)
+	if err != nil {
+		writeSessionError(w, err, "Failed to fetch session events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetSessionAt rebuilds the layout as of ?t=, an RFC 3339 timestamp that
+// defaults to now. Positions are in inches. Times before the history kept by
+// revision retention are 410 Gone.
+func (h *SessionHandler) GetSessionAt(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	at := time.Now()
+	if t := req.URL.Query().Get("t"); t != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			http.Error(w, "t must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		at = parsed
+	}
+
+	sessionId := req.PathValue("id")
+	layout, err := h.sessionService.GetSessionAt(req.Context(), sessionId, at)
+	if err != nil {
+		writeSessionError(w, err, "Failed to rebuild session")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(layout)
+}
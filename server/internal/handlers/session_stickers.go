@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"server/internal/models"
+	"server/internal/services"
+)
+
+// SessionStickers serves GET and POST /sessions/{id}/stickers. Positions are
+// in inches. Writes require If-Match with the current session version, like
+// PATCH, and return the new version as ETag.
+func (h *SessionHandler) SessionStickers(w http.ResponseWriter, req *http.Request) {
+	sessionId := req.PathValue("id")
+
+	switch req.Method {
+	case http.MethodGet:
+		stickers, err := h.sessionService.ListStickers(req.Context(), sessionId, req.URL.Query().Get("surfaceId"))
+		if err != nil {
+			writeSessionError(w, err, "Failed to fetch stickers")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(stickers)
+
+	case http.MethodPost:
+		expectedVersion, ok := readIfMatch(w, req, true)
+		if !ok {
+			return
+		}
+		author, ok := h.revisionAuthor(w, req)
+		if !ok {
+			return
+		}
+
+		var sticker models.PlacedSticker
+		if err := json.NewDecoder(req.Body).Decode(&sticker); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+
+		version, err := h.sessionService.AddSticker(req.Context(), sessionId, &sticker, author, expectedVersion)
+		if err != nil {
+			h.writeStickerWriteError(w, req, sessionId, err)
+			return
+		}
+
+		location := "/sessions/" + url.PathEscape(sessionId) + "/stickers/" + url.PathEscape(sticker.StickerId) +
+			"?surfaceId=" + url.QueryEscape(sticker.SurfaceId)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", location)
+		w.Header().Set("ETag", sessionETag(version))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(sticker)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SessionSticker serves GET, PUT and DELETE /sessions/{id}/stickers/{stickerId}.
+// The surface is picked with ?surfaceId= and defaults to the first one. PUT
+// replaces an existing placement and never creates one.
+func (h *SessionHandler) SessionSticker(w http.ResponseWriter, req *http.Request) {
+	sessionId := req.PathValue("id")
+	stickerId := req.PathValue("stickerId")
+	surfaceId := req.URL.Query().Get("surfaceId")
+
+	switch req.Method {
+	case http.MethodGet:
+		sticker, err := h.sessionService.GetSticker(req.Context(), sessionId, surfaceId, stickerId)
+		if err != nil {
+			writeSessionError(w, err, "Failed to fetch sticker")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(sticker)
+
+	case http.MethodPut:
+		expectedVersion, ok := readIfMatch(w, req, true)
+		if !ok {
+			return
+		}
+		author, ok := h.revisionAuthor(w, req)
+		if !ok {
+			return
+		}
+
+		var sticker models.PlacedSticker
+		if err := json.NewDecoder(req.Body).Decode(&sticker); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+		if sticker.StickerId == "" {
+			sticker.StickerId = stickerId
+		}
+		if sticker.SurfaceId == "" {
+			sticker.SurfaceId = surfaceId
+		}
+		if sticker.StickerId != stickerId || (surfaceId != "" && sticker.SurfaceId != surfaceId) {
+			writeSessionError(w, services.ErrInvalidStickerId, "")
+			return
+		}
+
+		version, err := h.sessionService.UpdateSticker(req.Context(), sessionId, &sticker, author, expectedVersion)
+		if err != nil {
+			h.writeStickerWriteError(w, req, sessionId, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", sessionETag(version))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(sticker)
+
+	case http.MethodDelete:
+		expectedVersion, ok := readIfMatch(w, req, true)
+		if !ok {
+			return
+		}
+		author, ok := h.revisionAuthor(w, req)
+		if !ok {
+			return
+		}
+
+		version, err := h.sessionService.DeleteSticker(req.Context(), sessionId, surfaceId, stickerId, author, expectedVersion)
+		if err != nil {
+			h.writeStickerWriteError(w, req, sessionId, err)
+			return
+		}
+
+		w.Header().Set("ETag", sessionETag(version))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *SessionHandler) writeStickerWriteError(w http.ResponseWriter, req *http.Request, sessionId string, err error) {
+	if errors.Is(err, services.ErrVersionConflict) {
+		h.writeVersionConflict(w, req, sessionId, false)
+		return
+	}
+	writeSessionError(w, err, "Failed to update sticker")
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		required bool
+		want     int
+		wantErr  error
+	}{
+		{"strong tag", `"3"`, true, 3, nil},
+		{"weak tag", `W/"3"`, true, 3, nil},
+		{"bare number", `12`, true, 12, nil},
+		{"surrounding spaces", `  "7" `, true, 7, nil},
+		{"missing when required", ``, true, 0, errPreconditionRequired},
+		{"wildcard when required", `*`, true, 0, errPreconditionRequired},
+		{"missing when optional", ``, false, 0, nil},
+		{"wildcard when optional", `*`, false, 0, nil},
+		{"zero", `"0"`, true, 0, errMalformed},
+		{"negative", `"-1"`, true, 0, errMalformed},
+		{"not a number", `"abc"`, true, 0, errMalformed},
+		{"several tags", `"1", "2"`, true, 0, errMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v2/save-session", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+
+			got, err := parseIfMatch(req, tt.required)
+			switch {
+			case tt.wantErr == errMalformed:
+				if err == nil || errors.Is(err, errPreconditionRequired) {
+					t.Errorf("parseIfMatch() error = %v, want a malformed header error", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("parseIfMatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseIfMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// errMalformed marks cases where parseIfMatch must fail with its malformed
+// header error, which is not a sentinel.
+var errMalformed = errors.New("malformed")
+
+func TestReadIfMatchStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusPreconditionRequired},
+		{"wildcard", "*", http.StatusPreconditionRequired},
+		{"malformed", `"x"`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/save-session", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			if _, ok := readIfMatch(w, req, true); ok {
+				t.Fatal("readIfMatch() accepted the header")
+			}
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"server/internal/models"
 	"server/internal/services"
@@ -34,6 +38,23 @@ func validateStickerMuleURL(urlStr string) bool {
 	return pattern.MatchString(parsedURL.Path)
 }
 
+// Largest side, in inches, accepted for an external sticker.
+const maxExternalStickerInches = 48
+
+func validateExternalImageURL(urlStr string) bool {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	return parsedURL.Scheme == "https" && parsedURL.Hostname() != "" && parsedURL.User == nil
+}
+
+func validateStickerSize(size models.Size) bool {
+	return size.Width > 0 && size.Height > 0 &&
+		size.Width <= maxExternalStickerInches && size.Height <= maxExternalStickerInches
+}
+
 func (h *StickerHandler) ProcessStickerURL(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -51,7 +72,7 @@ func (h *StickerHandler) ProcessStickerURL(w http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	stickerData, err := h.stickerService.FetchProductInfo(dat.URL)
+	stickerData, err := h.stickerService.FetchProductInfo(req.Context(), dat.URL)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		if err.Error() == "product is not a sticker" {
@@ -68,3 +89,94 @@ func (h *StickerHandler) ProcessStickerURL(w http.ResponseWriter, req *http.Requ
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(stickerData)
 }
+
+func (h *StickerHandler) ProcessExternalImageURL(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.ExternalImageRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !validateExternalImageURL(dat.URL) {
+		http.Error(w, "Invalid image URL, only https URLs are supported", http.StatusBadRequest)
+		return
+	}
+
+	if !validateStickerSize(dat.Size) {
+		http.Error(w, "Invalid sticker size", http.StatusBadRequest)
+		return
+	}
+
+	stickerData, err := h.stickerService.ProcessExternalImage(req.Context(), dat.URL, dat.Size)
+	if err != nil {
+		writeImageError(w, err, "URL does not point to a supported public image")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stickerData)
+}
+
+// UploadImage serves POST /upload-image. The body is the image itself, sent
+// with its image/* Content-Type, and ?width= and ?height= give the sticker
+// size in inches. The image is mirrored like one from /process-image-url.
+func (h *StickerHandler) UploadImage(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		http.Error(w, "Request body must be an image", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	query := req.URL.Query()
+	var size models.Size
+	size.Width, err = strconv.ParseFloat(query.Get("width"), 64)
+	if err == nil {
+		size.Height, err = strconv.ParseFloat(query.Get("height"), 64)
+	}
+	if err != nil || !validateStickerSize(size) {
+		http.Error(w, "Invalid sticker size", http.StatusBadRequest)
+		return
+	}
+
+	stickerData, err := h.stickerService.ProcessUploadedImage(req.Context(), req.Body, size)
+	if err != nil {
+		writeImageError(w, err, "Body is not a supported image")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stickerData)
+}
+
+// writeImageError writes the response for a failed external image or
+// upload. notAnImage is the message for data that is not a usable image.
+func writeImageError(w http.ResponseWriter, err error, notAnImage string) {
+	status, message := http.StatusBadRequest, ""
+	switch {
+	case errors.Is(err, services.ErrNotAnImage) || errors.Is(err, services.ErrBlockedImageAddress):
+		message = notAnImage
+	case errors.Is(err, services.ErrImageTooLarge):
+		message = "Image dimensions are too large"
+	case errors.Is(err, services.ErrImageFileTooLarge):
+		status, message = http.StatusRequestEntityTooLarge, "Image file is too large"
+	default:
+		http.Error(w, "Failed to fetch or store image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
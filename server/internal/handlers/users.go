@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/internal/models"
+	"server/internal/services"
+)
+
+// loginCookieName is the cookie holding the login session token.
+const loginCookieName = "login"
+
+type UserHandler struct {
+	userService *services.UserService
+}
+
+func NewUserHandler(userService *services.UserService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+	}
+}
+
+// writeUserError maps user service errors to HTTP responses, falling back to
+// a 500 with the given message.
+func writeUserError(w http.ResponseWriter, err error, fallback string) {
+	var retry *services.RetryError
+	switch {
+	case errors.As(err, &retry):
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.RetryAfter.Seconds())+1))
+		http.Error(w, "Too many failed attempts", http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrInvalidEmail):
+		http.Error(w, "Email address is invalid", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidPassword):
+		http.Error(w, "Password must be 8-72 characters", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidDisplayName):
+		http.Error(w, "Display name is too long", http.StatusBadRequest)
+	case errors.Is(err, services.ErrEmailTaken):
+		http.Error(w, "Email is already registered", http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidLogin):
+		http.Error(w, "Wrong email or password", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrNotLoggedIn):
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// currentUser returns the user the request's login cookie belongs to, or nil
+// if the request is anonymous or the login expired.
+func currentUser(req *http.Request, userService *services.UserService) (*models.User, error) {
+	cookie, err := req.Cookie(loginCookieName)
+	if err != nil {
+		return nil, nil
+	}
+
+	user, err := userService.Authenticate(req.Context(), cookie.Value)
+	if errors.Is(err, services.ErrNotLoggedIn) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// requireUser returns the logged-in user, writing a 401 and returning false
+// if the request is anonymous.
+func requireUser(w http.ResponseWriter, req *http.Request, userService *services.UserService) (*models.User, bool) {
+	user, err := currentUser(req, userService)
+	if err != nil {
+		writeUserError(w, err, "Failed to check login")
+		return nil, false
+	}
+	if user == nil {
+		writeUserError(w, services.ErrNotLoggedIn, "")
+		return nil, false
+	}
+	return user, true
+}
+
+// setLoginCookie hands the login to the browser. The client is served from
+// another site, hence SameSite=None; middleware.CORS refuses writes from
+// other origins in its place.
+func setLoginCookie(w http.ResponseWriter, login *services.LoginSession) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookieName,
+		Value:    login.Token,
+		Path:     "/",
+		Expires:  login.ExpiresAt,
+		MaxAge:   int(time.Until(login.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// Signup registers a user, logs them in and returns the user.
+func (h *UserHandler) Signup(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.SignupRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.userService.Signup(req.Context(), &dat)
+	if err != nil {
+		writeUserError(w, err, "Failed to sign up")
+		return
+	}
+
+	setLoginCookie(w, login)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(login.User)
+}
+
+// Login checks the email and password, sets the login cookie and returns the
+// user.
+func (h *UserHandler) Login(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dat models.LoginRequest
+	if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.userService.Login(req.Context(), &dat, clientIP(req))
+	if err != nil {
+		writeUserError(w, err, "Failed to log in")
+		return
+	}
+
+	setLoginCookie(w, login)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(login.User)
+}
+
+// Logout ends the login session and clears the cookie. It succeeds even if
+// the request was not logged in.
+func (h *UserHandler) Logout(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := req.Cookie(loginCookieName); err == nil {
+		if err := h.userService.Logout(req.Context(), cookie.Value); err != nil {
+			writeUserError(w, err, "Failed to log out")
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me returns the logged-in user.
+func (h *UserHandler) Me(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
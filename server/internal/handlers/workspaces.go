@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"server/internal/models"
+	"server/internal/services"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *services.WorkspaceService
+	userService      *services.UserService
+}
+
+func NewWorkspaceHandler(workspaceService *services.WorkspaceService, userService *services.UserService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+		userService:      userService,
+	}
+}
+
+// writeWorkspaceError maps workspace service errors to HTTP responses,
+// falling back to a 500 with the given message.
+func writeWorkspaceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrNotLoggedIn):
+		http.Error(w, "Not logged in", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrWorkspaceRoleRequired):
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidWorkspaceName):
+		http.Error(w, "Workspace name must be 1-255 characters", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidRole):
+		http.Error(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidEmail):
+		http.Error(w, "Email address is invalid", http.StatusBadRequest)
+	case errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, "No user is registered with that email", http.StatusNotFound)
+	case errors.Is(err, services.ErrMemberNotFound):
+		http.Error(w, "Workspace member not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrMemberExists):
+		http.Error(w, "User is already a member", http.StatusConflict)
+	case errors.Is(err, services.ErrLastOwner):
+		http.Error(w, "A workspace needs at least one owner", http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// Workspaces serves /workspaces: GET lists the caller's workspaces and POST
+// creates one owned by the caller.
+func (h *WorkspaceHandler) Workspaces(w http.ResponseWriter, req *http.Request) {
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		workspaces, err := h.workspaceService.ListWorkspaces(req.Context(), user.Id)
+		if err != nil {
+			writeWorkspaceError(w, err, "Failed to list workspaces")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(workspaces)
+
+	case http.MethodPost:
+		var dat models.CreateWorkspaceRequest
+		if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+
+		workspace, err := h.workspaceService.CreateWorkspace(req.Context(), user.Id, &dat)
+		if err != nil {
+			writeWorkspaceError(w, err, "Failed to create workspace")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(workspace)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Workspace serves /workspaces/{id}: GET for members and DELETE for owners.
+// Sessions of a deleted workspace stay with their owners and tokens.
+func (h *WorkspaceHandler) Workspace(w http.ResponseWriter, req *http.Request) {
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+	workspaceId := req.PathValue("id")
+
+	switch req.Method {
+	case http.MethodGet:
+		workspace, err := h.workspaceService.GetWorkspace(req.Context(), user.Id, workspaceId)
+		if err != nil {
+			writeWorkspaceError(w, err, "Failed to fetch workspace")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(workspace)
+
+	case http.MethodDelete:
+		if err := h.workspaceService.DeleteWorkspace(req.Context(), user.Id, workspaceId); err != nil {
+			writeWorkspaceError(w, err, "Failed to delete workspace")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// WorkspaceMembers serves /workspaces/{id}/members: GET for members and POST,
+// which adds a registered user by email, for owners.
+func (h *WorkspaceHandler) WorkspaceMembers(w http.ResponseWriter, req *http.Request) {
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+	workspaceId := req.PathValue("id")
+
+	switch req.Method {
+	case http.MethodGet:
+		members, err := h.workspaceService.ListMembers(req.Context(), user.Id, workspaceId)
+		if err != nil {
+			writeWorkspaceError(w, err, "Failed to list workspace members")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(members)
+
+	case http.MethodPost:
+		var dat models.AddMemberRequest
+		if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+
+		member, err := h.workspaceService.AddMember(req.Context(), user.Id, workspaceId, &dat)
+		if err != nil {
+			writeWorkspaceError(w, err, "Failed to add workspace member")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(member)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// WorkspaceMember serves /workspaces/{id}/members/{userId}: PUT changes the
+// member's role and DELETE removes them. Both are for owners, except that
+// members may remove themselves.
+func (h *WorkspaceHandler) WorkspaceMember(w http.ResponseWriter, req *http.Request) {
+	user, ok := requireUser(w, req, h.userService)
+	if !ok {
+		return
+	}
+	workspaceId := req.PathValue("id")
+	memberId := req.PathValue("userId")
+
+	switch req.Method {
+	case http.MethodPut:
+		var dat models.UpdateMemberRequest
+		if err := json.NewDecoder(req.Body).Decode(&dat); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.workspaceService.UpdateMember(req.Context(), user.Id, workspaceId, memberId, dat.Role); err != nil {
+			writeWorkspaceError(w, err, "Failed to update workspace member")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if err := h.workspaceService.RemoveMember(req.Context(), user.Id, workspaceId, memberId); err != nil {
+			writeWorkspaceError(w, err, "Failed to remove workspace member")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
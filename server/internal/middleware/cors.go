@@ -1,24 +1,70 @@
 package middleware
 
-import "net/http"
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// allowedOrigins are the front ends that may call the API with credentials.
+var allowedOrigins = map[string]bool{
+	"http://localhost:3000":                          true,
+	"https://mule-fe-1027839195257.us-east4.run.app": true,
+}
 
 func CORS(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 
 		// Allow only specific origins
-		if origin == "http://localhost:3000" || origin == "https://mule-fe-1027839195257.us-east4.run.app" {
+		if allowedOrigins[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match, Authorization, X-Unlock-Token")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Location")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		if !checkWriteOrigin(w, r) {
+			return
+		}
+
 		next(w, r)
 	}
 }
+
+// checkWriteOrigin guards the login and unlock cookies, which are
+// SameSite=None because the client is served from another site, against
+// cross-site request forgery. Writes from a browser must come from an allowed
+// origin, and any body must be JSON or an image upload, which other sites
+// cannot send without a preflight. Requests without an Origin header, such as from curl, are not
+// sent by browsers across sites and pass. It writes the error response and
+// returns false if the request is refused.
+func checkWriteOrigin(w http.ResponseWriter, r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" && !allowedOrigins[origin] {
+		http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+		return false
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" && r.ContentLength == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/json" && !strings.HasPrefix(mediaType, "image/")) {
+		http.Error(w, "Request body must be application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package models
+
+type KeepOutZone struct {
+	Name    string     `json:"name"`
+	Polygon []Position `json:"polygon"`
+}
+
+type DeviceVariant struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Color           string `json:"color"`
+	BackgroundImage string `json:"backgroundImage,omitempty"`
+}
+
+// Device describes a surface stickers can be placed on. Lengths and polygon
+// coordinates are in inches from the top-left corner of the lid.
+type Device struct {
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	LidWidth     float64         `json:"lidWidth"`
+	LidHeight    float64         `json:"lidHeight"`
+	Surface      []Position      `json:"surface"`
+	KeepOutZones []KeepOutZone   `json:"keepOutZones"`
+	Variants     []DeviceVariant `json:"variants"`
+}
+
+type DevicesResponse struct {
+	Devices []Device `json:"devices"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type Image struct {
+	Hash        string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+	SourceURL   string
+	Palette     []PaletteColor
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// LibraryItem is a resolved sticker saved for reuse, either by a user
+// (OwnerId) or for a whole workspace (WorkspaceId). Size is in inches.
+type LibraryItem struct {
+	Id          int64          `json:"id"`
+	OwnerId     string         `json:"ownerId,omitempty"`
+	WorkspaceId string         `json:"workspaceId,omitempty"`
+	CreatedBy   string         `json:"createdBy,omitempty"`
+	Title       string         `json:"title"`
+	Notes       string         `json:"notes"`
+	Tags        []string       `json:"tags"`
+	URL         string         `json:"url"`
+	SourceURL   string         `json:"sourceUrl,omitempty"`
+	Source      string         `json:"source"`
+	Size        Size           `json:"size"`
+	ProductID   int64          `json:"productId,omitempty"`
+	ImageHash   string         `json:"imageHash,omitempty"`
+	Palette     []PaletteColor `json:"palette,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// SaveLibraryItemRequest saves a sticker as resolved by /process-sticker-url
+// or /process-image-url. URL is the image to place; SourceURL the page it was
+// scraped from, if any. An empty WorkspaceId saves to the personal library.
+// The image hash and palette are derived by the server from ProductID or the
+// /images asset, not taken from the client.
+type SaveLibraryItemRequest struct {
+	WorkspaceId string   `json:"workspaceId"`
+	Title       string   `json:"title"`
+	Notes       string   `json:"notes"`
+	Tags        []string `json:"tags"`
+	URL         string   `json:"url"`
+	SourceURL   string   `json:"sourceUrl"`
+	Source      string   `json:"source"`
+	Size        Size     `json:"size"`
+	ProductID   int64    `json:"productId"`
+}
+
+// UpdateLibraryItemRequest changes the descriptive fields of an item; nil
+// fields are left alone.
+type UpdateLibraryItemRequest struct {
+	Title *string   `json:"title"`
+	Notes *string   `json:"notes"`
+	Tags  *[]string `json:"tags"`
+}
+
+// ListLibraryRequest selects a page of the personal library, or of a
+// workspace's. Query searches titles and notes; Tags must all be present.
+type ListLibraryRequest struct {
+	WorkspaceId string
+	Query       string
+	Tags        []string
+	Cursor      string
+	Limit       int
+}
+
+type LibraryItemList struct {
+	Items      []LibraryItem `json:"items"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// PlaceLibraryItemRequest places a library item on a session. An empty
+// StickerId gets a random one; an empty SurfaceId means the first surface.
+// Position is in inches.
+type PlaceLibraryItemRequest struct {
+	ItemId    int64     `json:"itemId"`
+	SurfaceId string    `json:"surfaceId"`
+	StickerId string    `json:"stickerId"`
+	Position  Position  `json:"position"`
+	Transform Transform `json:"transform"`
+}
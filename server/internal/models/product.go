@@ -0,0 +1,21 @@
+package models
+
+type Product struct {
+	ID             int64          `json:"id"`
+	SourceURL      string         `json:"sourceUrl"`
+	ProductImage   string         `json:"productImage"`
+	ImageHash      string         `json:"imageHash,omitempty"`
+	Size           Size           `json:"size"`
+	Palette        []PaletteColor `json:"palette,omitempty"`
+	PerceptualHash *int64         `json:"-"`
+}
+
+type NearDuplicate struct {
+	Product
+	Distance int `json:"distance"`
+}
+
+type NearDuplicatesResponse struct {
+	Product    Product         `json:"product"`
+	Duplicates []NearDuplicate `json:"duplicates"`
+}
@@ -1,10 +1,259 @@
 package models
 
+import "time"
+
+type Session struct {
+	Id          string         `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	OwnerId     string         `json:"ownerId,omitempty"`
+	WorkspaceId string         `json:"workspaceId,omitempty"`
+	DeviceId    string         `json:"deviceId"`
+	Settings    map[string]any `json:"settings"`
+	Version     int            `json:"version"`
+	ForkedFrom  string         `json:"forkedFrom,omitempty"`
+	Tags        []string       `json:"tags"`
+	ExpiresAt   *time.Time     `json:"expiresAt,omitempty"`
+	DeletedAt   *time.Time     `json:"deletedAt,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+type SaveSessionResponse struct {
+	Status  string `json:"status"`
+	Version int    `json:"version"`
+}
+
+// SessionSnapshot is the full layout of a session as recorded in a revision.
+type SessionSnapshot struct {
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Settings    map[string]any   `json:"settings"`
+	Surfaces    []SessionSurface `json:"surfaces"`
+}
+
+// SessionRevision is an immutable record of a session after one save.
+// Snapshot is left out when listing revisions.
+type SessionRevision struct {
+	Version   int              `json:"version"`
+	Author    string           `json:"author"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Snapshot  *SessionSnapshot `json:"snapshot,omitempty"`
+}
+
+// PlacedSticker is a single placement as served by the
+// /sessions/{id}/stickers routes, together with the surface it is on.
+type PlacedSticker struct {
+	SurfaceId string `json:"surfaceId"`
+	SavedStickerData
+}
+
+// Operations accepted by PATCH /sessions/{id}.
+const (
+	PatchOpAdd       = "add"
+	PatchOpMove      = "move"
+	PatchOpTransform = "transform"
+	PatchOpReorder   = "reorder"
+	PatchOpRemove    = "remove"
+)
+
+// SessionPatchOperation changes a single placement. Placements are addressed
+// by SurfaceId and StickerId; an empty SurfaceId means the first surface. Which
+// of the remaining fields are read depends on Op: Sticker for add, Position
+// for move, Transform for transform and ZIndex for reorder.
+type SessionPatchOperation struct {
+	Op        string            `json:"op"`
+	SurfaceId string            `json:"surfaceId,omitempty"`
+	StickerId string            `json:"stickerId,omitempty"`
+	Sticker   *SavedStickerData `json:"sticker,omitempty"`
+	Position  *Position         `json:"position,omitempty"`
+	Transform *Transform        `json:"transform,omitempty"`
+	ZIndex    *int              `json:"zIndex,omitempty"`
+}
+
+type PatchSessionRequest struct {
+	Operations []SessionPatchOperation `json:"operations"`
+}
+
+// SessionTokens are the secrets granting access to a protected session. They
+// are only returned when issued; the server keeps hashes. An empty token was
+// not issued or has been revoked.
+type SessionTokens struct {
+	ViewToken string `json:"viewToken,omitempty"`
+	EditToken string `json:"editToken,omitempty"`
+}
+
+// SessionWithTokens is returned when a session is created or forked.
+type SessionWithTokens struct {
+	*Session
+	Tokens SessionTokens `json:"tokens"`
+}
+
+// SessionAccess is what the server stores to check tokens against.
+// PasscodeHash is a bcrypt hash, nil when the session has no passcode.
+// WorkspaceRole is the role of the requesting user in the session's
+// workspace, empty if either is missing.
+type SessionAccess struct {
+	Protected     bool
+	ViewTokenHash []byte
+	EditTokenHash []byte
+	PasscodeHash  []byte
+	OwnerId       string
+	WorkspaceRole string
+	DeletedAt     *time.Time
+	ExpiresAt     *time.Time
+}
+
+type SetTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// ListSessionsRequest selects a page of sessions, either the caller's own
+// (Owner "me") or those of a workspace. Tags must all be present;
+// Query is a web-search style full-text query over session and sticker
+// titles. Cursor is the NextCursor of the previous page.
+type ListSessionsRequest struct {
+	Owner       string
+	WorkspaceId string
+	Query       string
+	DeviceId    string
+	Tags        []string
+	Sort        string
+	Cursor      string
+	Limit       int
+}
+
+type SessionList struct {
+	Sessions   []Session `json:"sessions"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// SetExpiryRequest sets when a session expires; null removes the expiry.
+type SetExpiryRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type SetPasscodeRequest struct {
+	Passcode string `json:"passcode"`
+}
+
+type UnlockSessionRequest struct {
+	Passcode string `json:"passcode"`
+}
+
+// UnlockSessionResponse carries the unlock token for clients that cannot use
+// the cookie set alongside it.
+type UnlockSessionResponse struct {
+	UnlockToken string    `json:"unlockToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// ForkSessionRequest optionally overrides the title of the fork, which
+// otherwise keeps the title of the original. Author is recorded on the fork's
+// first revision; it is set from the login, never read from the body.
+type ForkSessionRequest struct {
+	Title  *string `json:"title,omitempty"`
+	Author string  `json:"-"`
+}
+
+// SessionLineage lists where a session was forked from, nearest first, and
+// the sessions forked from it. Only sessions the caller can view are shown
+// in full: other ancestors keep just their id, and other forks are counted
+// in HiddenForks.
+type SessionLineage struct {
+	Ancestors   []LineageSession `json:"ancestors"`
+	Forks       []Session        `json:"forks"`
+	HiddenForks int              `json:"hiddenForks"`
+}
+
+// LineageSession is an ancestor in a lineage. Session is nil when the caller
+// cannot view it.
+type LineageSession struct {
+	Id      string   `json:"id"`
+	Session *Session `json:"session,omitempty"`
+}
+
+type CreateSessionRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	DeviceId    string     `json:"deviceId"`
+	WorkspaceId string     `json:"workspaceId"`
+	Tags        []string   `json:"tags"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+}
+
+// SessionSurface is one named object in a session, such as a laptop or a
+// water bottle, with its own device template and sticker placements.
+type SessionSurface struct {
+	Id            string             `json:"id"`
+	Name          string             `json:"name"`
+	DeviceId      string             `json:"deviceId"`
+	DeviceVariant string             `json:"deviceVariant,omitempty"`
+	Stickers      []SavedStickerData `json:"stickers"`
+}
+
 type SaveSessionRequest struct {
-	SessionId string             `json:"sessionId"`
-	Stickers  []SavedStickerData `json:"stickers"`
+	SessionId string           `json:"sessionId"`
+	Surfaces  []SessionSurface `json:"surfaces,omitempty"`
+
+	// Metadata fields left out of the request keep their stored values.
+	Title       *string        `json:"title,omitempty"`
+	Description *string        `json:"description,omitempty"`
+	Settings    map[string]any `json:"settings,omitempty"`
+
+	// Author is recorded on the revision the save creates. It is set from
+	// the login, never read from the body.
+	Author string `json:"-"`
+
+	// Stickers, DeviceId and DeviceVariant are for clients that predate
+	// surfaces. They are only read when Surfaces is empty and replace the
+	// placements and, if DeviceId is set, the device of the first surface.
+	Stickers      []SavedStickerData `json:"stickers,omitempty"`
+	DeviceId      string             `json:"deviceId,omitempty"`
+	DeviceVariant string             `json:"deviceVariant,omitempty"`
 }
 
 type GetSessionDataResponse struct {
-	Stickers []SavedStickerData `json:"stickers"`
+	// Session is nil when nothing has been saved under the ID yet.
+	Session  *Session         `json:"session"`
+	Surfaces []SessionSurface `json:"surfaces"`
+
+	// Stickers, DeviceId and DeviceVariant mirror the first surface for
+	// clients that predate surfaces.
+	Stickers      []SavedStickerData `json:"stickers"`
+	DeviceId      string             `json:"deviceId"`
+	DeviceVariant string             `json:"deviceVariant,omitempty"`
+}
+
+type StickerPalette struct {
+	Size    Size           `json:"size"`
+	Palette []PaletteColor `json:"palette"`
+}
+
+type SessionPaletteResponse struct {
+	Palette  []PaletteColor `json:"palette"`
+	Stickers int            `json:"stickers"`
+}
+
+type StickerOverlap struct {
+	StickerIds [2]string `json:"stickerIds"`
+	Area       float64   `json:"area"`
+}
+
+type SessionCoverageResponse struct {
+	SurfaceArea     float64          `json:"surfaceArea"`
+	StickerArea     float64          `json:"stickerArea"`
+	CoveredArea     float64          `json:"coveredArea"`
+	CoveragePercent float64          `json:"coveragePercent"`
+	Overlaps        []StickerOverlap `json:"overlaps"`
+}
+
+// JanitorStats are the session janitor's totals since the server started.
+type JanitorStats struct {
+	PurgedDeleted   int64      `json:"purgedDeleted"`
+	PurgedExpired   int64      `json:"purgedExpired"`
+	PurgedAbandoned int64      `json:"purgedAbandoned"`
+	Sweeps          int64      `json:"sweeps"`
+	Errors          int64      `json:"errors"`
+	LastSweep       *time.Time `json:"lastSweep"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Types of entries in a session's edit log.
+const (
+	SessionEventLayoutReplaced     = "layout_replaced"
+	SessionEventStickerAdded       = "sticker_added"
+	SessionEventStickerMoved       = "sticker_moved"
+	SessionEventStickerTransformed = "sticker_transformed"
+	SessionEventStickerReordered   = "sticker_reordered"
+	SessionEventStickerUpdated     = "sticker_updated"
+	SessionEventStickerRemoved     = "sticker_removed"
+)
+
+// SessionEvent is one append-only entry in a session's edit log. Version is
+// the session version the mutation produced; a patch records several events
+// under the same version.
+type SessionEvent struct {
+	Id        int64            `json:"id"`
+	Version   int              `json:"version"`
+	Type      string           `json:"type"`
+	SurfaceId string           `json:"surfaceId,omitempty"`
+	StickerId string           `json:"stickerId,omitempty"`
+	Data      SessionEventData `json:"data"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// SessionEventData holds the fields of an event that depend on its type:
+// Surfaces for layout_replaced, Sticker for sticker_added and sticker_updated,
+// Position for sticker_moved, Transform for sticker_transformed and ZIndex
+// for sticker_reordered.
+type SessionEventData struct {
+	Surfaces  []SessionSurface  `json:"surfaces,omitempty"`
+	Sticker   *SavedStickerData `json:"sticker,omitempty"`
+	Position  *Position         `json:"position,omitempty"`
+	Transform *Transform        `json:"transform,omitempty"`
+	ZIndex    *int              `json:"zIndex,omitempty"`
+}
+
+// SessionLayoutAt is the layout of a session rebuilt from its edit log as of
+// a point in time.
+type SessionLayoutAt struct {
+	At       time.Time        `json:"at"`
+	Version  int              `json:"version"`
+	Surfaces []SessionSurface `json:"surfaces"`
+}
@@ -1,9 +1,19 @@
 package models
 
+const (
+	StickerSourceStickerMule = "stickermule"
+	StickerSourceExternal    = "external"
+)
+
 type StickerURLRequest struct {
 	URL string `json:"url"`
 }
 
+type ExternalImageRequest struct {
+	URL  string `json:"url"`
+	Size Size   `json:"size"`
+}
+
 type Size struct {
 	Width  float64 `json:"width"`
 	Height float64 `json:"height"`
@@ -14,14 +24,42 @@ type Position struct {
 	Y float64 `json:"y"`
 }
 
+type PaletteColor struct {
+	Hex    string  `json:"hex"`
+	Weight float64 `json:"weight"`
+}
+
+// Transform is applied around the sticker's center, in the order scale,
+// rotate, flip, like the equivalent CSS transform.
+type Transform struct {
+	Rotation float64 `json:"rotation"` // degrees, clockwise
+	Scale    float64 `json:"scale"`
+	FlipX    bool    `json:"flipX"`
+	FlipY    bool    `json:"flipY"`
+	Opacity  float64 `json:"opacity"`
+}
+
 type StickerDataResponse struct {
-	ProductImage string `json:"productImage"`
-	Size         Size   `json:"size"`
+	Title         string            `json:"title,omitempty"`
+	ProductImage  string            `json:"productImage"`
+	Size          Size              `json:"size"`
+	Source        string            `json:"source"`
+	ImageSize     *Size             `json:"imageSize,omitempty"`
+	CorrectedSize *Size             `json:"correctedSize,omitempty"`
+	Warning       string            `json:"warning,omitempty"`
+	ProductID     int64             `json:"productId,omitempty"`
+	ImageHash     string            `json:"imageHash,omitempty"`
+	Images        map[string]string `json:"images,omitempty"`
+	Palette       []PaletteColor    `json:"palette,omitempty"`
 }
 
 type SavedStickerData struct {
-	StickerId string   `json:"stickerId"`
-	URL       string   `json:"url"`
-	Size      Size     `json:"size"`
-	Position  Position `json:"position"`
+	StickerId string    `json:"stickerId"`
+	Title     string    `json:"title,omitempty"`
+	URL       string    `json:"url"`
+	Size      Size      `json:"size"`
+	Position  Position  `json:"position"`
+	Source    string    `json:"source,omitempty"`
+	ZIndex    int       `json:"zIndex"`
+	Transform Transform `json:"transform"`
 }
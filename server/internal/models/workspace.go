@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Workspace roles, from most to least privileged. Owners manage members,
+// editors can edit every session of the workspace and viewers can view them.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+// Workspace is a team's shared space. Role is the caller's role in it.
+type Workspace struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WorkspaceMember struct {
+	UserId      string    `json:"userId"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"displayName"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// AddMemberRequest invites a registered user by email.
+type AddMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// MoveSessionRequest moves a session into a workspace; an empty WorkspaceId
+// makes it personal again.
+type MoveSessionRequest struct {
+	WorkspaceId string `json:"workspaceId"`
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+	"time"
+)
+
+// AccessLevel is what a request may do with a session.
+type AccessLevel int
+
+const (
+	AccessNone AccessLevel = iota
+	AccessView
+	AccessEdit
+)
+
+var (
+	ErrAccessDenied            = errors.New("a valid session token is required")
+	ErrEditAccessRequired      = errors.New("this token only grants view access")
+	ErrSessionAlreadyProtected = errors.New("session already has tokens")
+	ErrInvalidTokenKind        = errors.New("token kind must be view or edit")
+	ErrSessionOwnerRequired    = errors.New("only the owner of the session or of its workspace can do this")
+)
+
+// Kinds of token accepted by RegenerateToken and RevokeToken.
+const (
+	TokenKindView = "view"
+	TokenKindEdit = "edit"
+)
+
+const sessionTokenBytes = 32
+
+func generateSessionToken() (string, []byte, error) {
+	raw := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashSessionToken(token), nil
+}
+
+func hashSessionToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// newSessionTokens issues a view and an edit token and returns them with the
+// hashes to store.
+func newSessionTokens() (models.SessionTokens, *models.SessionAccess, error) {
+	viewToken, viewHash, err := generateSessionToken()
+	if err != nil {
+		return models.SessionTokens{}, nil, err
+	}
+	editToken, editHash, err := generateSessionToken()
+	if err != nil {
+		return models.SessionTokens{}, nil, err
+	}
+
+	tokens := models.SessionTokens{ViewToken: viewToken, EditToken: editToken}
+	access := &models.SessionAccess{Protected: true, ViewTokenHash: viewHash, EditTokenHash: editHash}
+	return tokens, access, nil
+}
+
+// accessLevel returns the most the credentials grant. Owners can always edit
+// their sessions, and members of the session's workspace get what their role
+// allows. Sessions from before tokens existed are open to anyone until tokens
+// are claimed for them.
+func accessLevel(access *models.SessionAccess, creds Credentials) AccessLevel {
+	if !access.Protected {
+		return AccessEdit
+	}
+	if access.OwnerId != "" && creds.UserId == access.OwnerId {
+		return AccessEdit
+	}
+
+	level := roleAccessLevel(access.WorkspaceRole)
+	if creds.Token == "" || level == AccessEdit {
+		return level
+	}
+
+	hash := hashSessionToken(creds.Token)
+	if access.EditTokenHash != nil && subtle.ConstantTimeCompare(hash, access.EditTokenHash) == 1 {
+		return AccessEdit
+	}
+	if access.ViewTokenHash != nil && subtle.ConstantTimeCompare(hash, access.ViewTokenHash) == 1 {
+		return max(level, AccessView)
+	}
+	return level
+}
+
+// roleAccessLevel is what a workspace role grants on the workspace's
+// sessions.
+func roleAccessLevel(role string) AccessLevel {
+	switch role {
+	case models.WorkspaceRoleOwner, models.WorkspaceRoleEditor:
+		return AccessEdit
+	case models.WorkspaceRoleViewer:
+		return AccessView
+	}
+	return AccessNone
+}
+
+// Authorize checks that the credentials grant at least the needed access to
+// the session, including a valid unlock token if it has a passcode.
+// Deleted and expired sessions are reported as not found.
+func (s *SessionService) Authorize(ctx context.Context, sessionId string, creds Credentials, need AccessLevel) error {
+	access, err := s.checkAccess(ctx, sessionId, creds, need)
+	if err != nil {
+		return err
+	}
+	if !isLive(access, time.Now()) {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// checkAccess is Authorize without the check that the session is live.
+func (s *SessionService) checkAccess(ctx context.Context, sessionId string, creds Credentials, need AccessLevel) (*models.SessionAccess, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	access, err := s.dbClient.GetSessionAccess(ctx, sessionId, creds.UserId)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	level := accessLevel(access, creds)
+	switch {
+	case level == AccessNone:
+		return nil, ErrAccessDenied
+	case level < need:
+		return nil, ErrEditAccessRequired
+	}
+
+	if access.PasscodeHash != nil && !s.validUnlockToken(sessionId, access.PasscodeHash, creds.UnlockToken, time.Now()) {
+		return nil, ErrSessionLocked
+	}
+	return access, nil
+}
+
+// ClaimSession issues tokens for a session created before tokens existed.
+// Until then anyone with its ID can edit it, so the first caller wins.
+func (s *SessionService) ClaimSession(ctx context.Context, sessionId string) (models.SessionTokens, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return models.SessionTokens{}, ErrInvalidSessionId
+	}
+
+	tokens, access, err := newSessionTokens()
+	if err != nil {
+		return models.SessionTokens{}, err
+	}
+
+	err = s.dbClient.ClaimSession(ctx, sessionId, access)
+	switch {
+	case errors.Is(err, db.ErrSessionNotFound):
+		return models.SessionTokens{}, ErrSessionNotFound
+	case errors.Is(err, db.ErrSessionProtected):
+		return models.SessionTokens{}, ErrSessionAlreadyProtected
+	case err != nil:
+		return models.SessionTokens{}, err
+	}
+	return tokens, nil
+}
+
+// RegenerateToken replaces the view or edit token, invalidating links that
+// use the old one, and returns the new token. Owned sessions need their owner
+// or the owner of their workspace.
+func (s *SessionService) RegenerateToken(ctx context.Context, sessionId, kind, userId string) (models.SessionTokens, error) {
+	if err := s.requireSessionOwner(ctx, sessionId, userId); err != nil {
+		return models.SessionTokens{}, err
+	}
+
+	token, hash, err := generateSessionToken()
+	if err != nil {
+		return models.SessionTokens{}, err
+	}
+
+	switch kind {
+	case TokenKindView:
+		err = s.dbClient.SetSessionViewToken(ctx, sessionId, hash)
+		return models.SessionTokens{ViewToken: token}, translateTokenError(err)
+	case TokenKindEdit:
+		err = s.dbClient.SetSessionEditToken(ctx, sessionId, hash)
+		return models.SessionTokens{EditToken: token}, translateTokenError(err)
+	}
+	return models.SessionTokens{}, ErrInvalidTokenKind
+}
+
+// RevokeViewToken disables view links until a new view token is generated.
+// The edit token can only be rotated, so a session always has an editor.
+// Owned sessions need their owner or the owner of their workspace.
+func (s *SessionService) RevokeViewToken(ctx context.Context, sessionId, userId string) error {
+	if err := s.requireSessionOwner(ctx, sessionId, userId); err != nil {
+		return err
+	}
+	return translateTokenError(s.dbClient.SetSessionViewToken(ctx, sessionId, nil))
+}
+
+// requireSessionOwner checks that the user owns the session or its
+// workspace. Sessions without an owner are managed by whoever can edit them,
+// so any caller passes for those.
+func (s *SessionService) requireSessionOwner(ctx context.Context, sessionId, userId string) error {
+	access, err := s.dbClient.GetSessionAccess(ctx, sessionId, userId)
+	if err != nil {
+		return translateTokenError(err)
+	}
+	return checkSessionOwner(access, userId)
+}
+
+// checkSessionOwner is requireSessionOwner for access already loaded for the
+// user.
+func checkSessionOwner(access *models.SessionAccess, userId string) error {
+	switch {
+	case access.OwnerId == "":
+		return nil
+	case userId == "":
+		return ErrNotLoggedIn
+	case access.OwnerId != userId && access.WorkspaceRole != models.WorkspaceRoleOwner:
+		return ErrSessionOwnerRequired
+	}
+	return nil
+}
+
+func translateTokenError(err error) error {
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
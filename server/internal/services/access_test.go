@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestAccessLevel(t *testing.T) {
+	protected := models.SessionAccess{
+		Protected:     true,
+		ViewTokenHash: hashSessionToken("view"),
+		EditTokenHash: hashSessionToken("edit"),
+		OwnerId:       "owner",
+	}
+	withRole := func(role string) *models.SessionAccess {
+		access := protected
+		access.WorkspaceRole = role
+		return &access
+	}
+
+	tests := []struct {
+		name   string
+		access *models.SessionAccess
+		creds  Credentials
+		want   AccessLevel
+	}{
+		{"unprotected session", &models.SessionAccess{}, Credentials{}, AccessEdit},
+		{"no credentials", &protected, Credentials{}, AccessNone},
+		{"view token", &protected, Credentials{Token: "view"}, AccessView},
+		{"edit token", &protected, Credentials{Token: "edit"}, AccessEdit},
+		{"wrong token", &protected, Credentials{Token: "guess"}, AccessNone},
+		{"owner", &protected, Credentials{UserId: "owner"}, AccessEdit},
+		{"other user", &protected, Credentials{UserId: "someone"}, AccessNone},
+		{"workspace viewer", withRole(models.WorkspaceRoleViewer), Credentials{UserId: "member"}, AccessView},
+		{"workspace viewer with edit token", withRole(models.WorkspaceRoleViewer), Credentials{UserId: "member", Token: "edit"}, AccessEdit},
+		{"workspace editor", withRole(models.WorkspaceRoleEditor), Credentials{UserId: "member"}, AccessEdit},
+		{"workspace owner", withRole(models.WorkspaceRoleOwner), Credentials{UserId: "member"}, AccessEdit},
+		{"unknown role", withRole("guest"), Credentials{UserId: "member"}, AccessNone},
+		{"view token with no view hash", &models.SessionAccess{Protected: true, EditTokenHash: hashSessionToken("edit")}, Credentials{Token: "view"}, AccessNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accessLevel(tt.access, tt.creds); got != tt.want {
+				t.Errorf("accessLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
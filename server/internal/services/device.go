@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+)
+
+// DefaultDeviceID is used for sessions that never selected a device, which
+// were all made on the original 14" MacBook Pro mockup.
+const DefaultDeviceID = "macbook-pro-14"
+
+var (
+	ErrDeviceNotFound        = errors.New("device not found")
+	ErrDeviceVariantNotFound = errors.New("device variant not found")
+)
+
+type DeviceService struct {
+	dbClient *db.Client
+}
+
+func NewDeviceService(dbClient *db.Client) *DeviceService {
+	return &DeviceService{
+		dbClient: dbClient,
+	}
+}
+
+func (s *DeviceService) ListDevices(ctx context.Context) (*models.DevicesResponse, error) {
+	devices, err := s.dbClient.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DevicesResponse{
+		Devices: devices,
+	}, nil
+}
+
+func (s *DeviceService) GetDevice(ctx context.Context, id string) (*models.Device, error) {
+	device, err := s.dbClient.GetDevice(ctx, id)
+	if errors.Is(err, db.ErrDeviceNotFound) {
+		return nil, ErrDeviceNotFound
+	}
+	return device, err
+}
+
+// ValidateSelection checks that the device exists and, if given, that the
+// variant belongs to it.
+func (s *DeviceService) ValidateSelection(ctx context.Context, deviceID string, variantID string) error {
+	device, err := s.GetDevice(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	if variantID == "" {
+		return nil
+	}
+	for _, variant := range device.Variants {
+		if variant.ID == variantID {
+			return nil
+		}
+	}
+	return ErrDeviceVariantNotFound
+}
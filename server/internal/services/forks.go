@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/db"
+	"server/internal/models"
+)
+
+// ForkSession copies a session into a new one under a fresh ID that links
+// back to the original. The fork gets its own tokens and, if ownerId is set,
+// belongs to that user.
+func (s *SessionService) ForkSession(ctx context.Context, sourceId string, req *models.ForkSessionRequest, ownerId string) (*models.SessionWithTokens, error) {
+	if !s.isWellFormedSessionId(sourceId) {
+		return nil, ErrInvalidSessionId
+	}
+	if req.Title != nil && len(*req.Title) > maxSessionTitleLength {
+		return nil, ErrInvalidSessionTitle
+	}
+
+	tokens, access, err := newSessionTokens()
+	if err != nil {
+		return nil, err
+	}
+	access.OwnerId = ownerId
+
+	for attempt := 0; attempt < maxSessionIdAttempts; attempt++ {
+		id, err := generateSessionId(s.idConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		fork, err := s.dbClient.ForkSession(ctx, sourceId, id, req.Title, req.Author, access)
+		if errors.Is(err, db.ErrSessionExists) {
+			continue
+		}
+		if errors.Is(err, db.ErrSessionNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &models.SessionWithTokens{Session: fork, Tokens: tokens}, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate an unused session id after %d attempts", maxSessionIdAttempts)
+}
+
+// GetLineage returns the ancestors and forks of a session, showing in full
+// only those the credentials grant view access to. Tokens and unlock tokens
+// belong to the requested session, so only the user carries over to the
+// others.
+func (s *SessionService) GetLineage(ctx context.Context, sessionId string, creds Credentials) (*models.SessionLineage, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	ancestors, forks, err := s.dbClient.GetSessionLineage(ctx, sessionId)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	others := Credentials{UserId: creds.UserId}
+	lineage := &models.SessionLineage{
+		Ancestors: make([]models.LineageSession, len(ancestors)),
+		Forks:     []models.Session{},
+	}
+
+	for i := range ancestors {
+		lineage.Ancestors[i].Id = ancestors[i].Id
+		visible, err := s.canView(ctx, ancestors[i].Id, others)
+		if err != nil {
+			return nil, err
+		}
+		if visible {
+			lineage.Ancestors[i].Session = &ancestors[i]
+		}
+	}
+
+	for _, fork := range forks {
+		visible, err := s.canView(ctx, fork.Id, others)
+		if err != nil {
+			return nil, err
+		}
+		if visible {
+			lineage.Forks = append(lineage.Forks, fork)
+		} else {
+			lineage.HiddenForks++
+		}
+	}
+
+	return lineage, nil
+}
+
+// canView reports whether the credentials grant view access to a session,
+// treating a locked or since deleted session as not viewable.
+func (s *SessionService) canView(ctx context.Context, sessionId string, creds Credentials) (bool, error) {
+	err := s.Authorize(ctx, sessionId, creds, AccessView)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrAccessDenied), errors.Is(err, ErrSessionLocked), errors.Is(err, ErrSessionNotFound):
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"math"
+	"server/internal/models"
+	"server/internal/utils"
+)
+
+// LegacyPixelsPerInch is the scale the original client rendered the 12.3in
+// lid of the 14" MacBook Pro at in its widest, 900px layout. The v1 API, which
+// exchanges positions in client pixels, converts with it; the v2 API uses
+// inches directly.
+const LegacyPixelsPerInch = 900 / 12.3
+
+func scalePositions(stickers []models.SavedStickerData, factor float64) {
+	for i := range stickers {
+		stickers[i].Position.X *= factor
+		stickers[i].Position.Y *= factor
+	}
+}
+
+func LegacyPixelsToInches(req *models.SaveSessionRequest) {
+	scalePositions(req.Stickers, 1/LegacyPixelsPerInch)
+	for i := range req.Surfaces {
+		scalePositions(req.Surfaces[i].Stickers, 1/LegacyPixelsPerInch)
+	}
+}
+
+// InchesToLegacyPixels converts every surface of the response. The top-level
+// Stickers share their backing array with the first surface and are converted
+// along with it.
+func InchesToLegacyPixels(resp *models.GetSessionDataResponse) {
+	for i := range resp.Surfaces {
+		scalePositions(resp.Surfaces[i].Stickers, LegacyPixelsPerInch)
+	}
+}
+
+// Side of the grid cells, in inches, used to estimate the union of sticker
+// footprints. Surfaces too large to sample within maxCoverageSamples cells at
+// that step are sampled on a coarser grid.
+const (
+	coverageSampleStep = 0.02
+	maxCoverageSamples = 250_000
+)
+
+// stickerFootprint returns the outline of a placed sticker. The position is
+// the top-left corner of the untransformed sticker and the transform is
+// applied around its center. Flips and opacity do not change the outline.
+func stickerFootprint(sticker models.SavedStickerData) utils.Polygon {
+	scale := sticker.Transform.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	center := utils.Point{
+		X: sticker.Position.X + sticker.Size.Width/2,
+		Y: sticker.Position.Y + sticker.Size.Height/2,
+	}
+	return utils.RotatedRect(center, sticker.Size.Width*scale, sticker.Size.Height*scale, sticker.Transform.Rotation)
+}
+
+func toPolygon(points []models.Position) utils.Polygon {
+	polygon := make(utils.Polygon, len(points))
+	for i, p := range points {
+		polygon[i] = utils.Point{X: p.X, Y: p.Y}
+	}
+	return polygon
+}
+
+// computeCoverage measures the stickers against the printable surface of the
+// device, which excludes its keep-out zones.
+func computeCoverage(stickers []models.SavedStickerData, device *models.Device) *models.SessionCoverageResponse {
+	surface := toPolygon(device.Surface)
+	keepOuts := make([]utils.Polygon, len(device.KeepOutZones))
+	for i, zone := range device.KeepOutZones {
+		keepOuts[i] = toPolygon(zone.Polygon)
+	}
+
+	footprints := make([]utils.Polygon, len(stickers))
+	boxes := make([]bounds, len(stickers))
+	var stickerArea float64
+	for i, sticker := range stickers {
+		footprints[i] = stickerFootprint(sticker)
+		boxes[i].min, boxes[i].max = footprints[i].Bounds()
+		stickerArea += footprints[i].Area()
+	}
+
+	overlaps := []models.StickerOverlap{}
+	for i := range footprints {
+		for j := i + 1; j < len(footprints); j++ {
+			if !boxes[i].overlaps(boxes[j]) {
+				continue
+			}
+			area := utils.IntersectConvex(footprints[i], footprints[j]).Area()
+			if area <= 0 {
+				continue
+			}
+			overlaps = append(overlaps, models.StickerOverlap{
+				StickerIds: [2]string{stickers[i].StickerId, stickers[j].StickerId},
+				Area:       roundArea(area),
+			})
+		}
+	}
+
+	// The union of overlapping footprints has no closed form, so it and the
+	// printable area are estimated by sampling the surface on a fine grid.
+	var surfaceCells, coveredCells int
+	min, max := surface.Bounds()
+	step := math.Max(coverageSampleStep, math.Sqrt((max.X-min.X)*(max.Y-min.Y)/maxCoverageSamples))
+	cellArea := step * step
+	for y := min.Y + step/2; y < max.Y; y += step {
+		for x := min.X + step/2; x < max.X; x += step {
+			pt := utils.Point{X: x, Y: y}
+			if !isPrintable(pt, surface, keepOuts) {
+				continue
+			}
+			surfaceCells++
+			for i, footprint := range footprints {
+				if boxes[i].contains(pt) && footprint.Contains(pt) {
+					coveredCells++
+					break
+				}
+			}
+		}
+	}
+	coveredArea := float64(coveredCells) * cellArea
+	surfaceArea := float64(surfaceCells) * cellArea
+
+	coveragePercent := 0.0
+	if surfaceArea > 0 {
+		coveragePercent = math.Round(coveredArea/surfaceArea*1000) / 10
+	}
+
+	return &models.SessionCoverageResponse{
+		SurfaceArea:     roundArea(surfaceArea),
+		StickerArea:     roundArea(stickerArea),
+		CoveredArea:     roundArea(coveredArea),
+		CoveragePercent: coveragePercent,
+		Overlaps:        overlaps,
+	}
+}
+
+// bounds is the axis-aligned bounding box of a footprint, checked before the
+// exact polygon tests.
+type bounds struct {
+	min, max utils.Point
+}
+
+func (b bounds) contains(pt utils.Point) bool {
+	return pt.X >= b.min.X && pt.X <= b.max.X && pt.Y >= b.min.Y && pt.Y <= b.max.Y
+}
+
+func (b bounds) overlaps(o bounds) bool {
+	return b.min.X < o.max.X && o.min.X < b.max.X && b.min.Y < o.max.Y && o.min.Y < b.max.Y
+}
+
+func isPrintable(pt utils.Point, surface utils.Polygon, keepOuts []utils.Polygon) bool {
+	if !surface.Contains(pt) {
+		return false
+	}
+	for _, keepOut := range keepOuts {
+		if keepOut.Contains(pt) {
+			return false
+		}
+	}
+	return true
+}
+
+func roundArea(area float64) float64 {
+	return math.Round(area*100) / 100
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"server/internal/models"
+)
+
+func rectangle(w, h float64) []models.Position {
+	return []models.Position{{X: 0, Y: 0}, {X: w, Y: 0}, {X: w, Y: h}, {X: 0, Y: h}}
+}
+
+func placed(id string, x, y, size float64) models.SavedStickerData {
+	return models.SavedStickerData{
+		StickerId: id,
+		Size:      models.Size{Width: size, Height: size},
+		Position:  models.Position{X: x, Y: y},
+	}
+}
+
+func TestComputeCoverage(t *testing.T) {
+	device := &models.Device{Surface: rectangle(10, 10)}
+	withKeepOut := &models.Device{
+		Surface:      rectangle(10, 10),
+		KeepOutZones: []models.KeepOutZone{{Name: "logo", Polygon: rectangle(5, 10)}},
+	}
+	// Sampled on a coarser grid than coverageSampleStep.
+	large := &models.Device{Surface: rectangle(100, 100)}
+
+	tests := []struct {
+		name         string
+		stickers     []models.SavedStickerData
+		device       *models.Device
+		surfaceArea  float64
+		coveredArea  float64
+		stickerArea  float64
+		overlapAreas []float64
+	}{
+		{"empty", nil, device, 100, 0, 0, nil},
+		{"one sticker", []models.SavedStickerData{placed("a", 1, 1, 2)}, device, 100, 4, 4, nil},
+		{"disjoint", []models.SavedStickerData{placed("a", 0, 0, 2), placed("b", 5, 5, 2)}, device, 100, 8, 8, nil},
+		{"overlapping", []models.SavedStickerData{placed("a", 0, 0, 2), placed("b", 1, 1, 2)}, device, 100, 7, 8, []float64{1}},
+		{"touching edges do not overlap", []models.SavedStickerData{placed("a", 0, 0, 2), placed("b", 2, 0, 2)}, device, 100, 8, 8, nil},
+		{"keep-out zone excluded", []models.SavedStickerData{placed("a", 4, 0, 2)}, withKeepOut, 50, 2, 4, nil},
+		{"large surface", []models.SavedStickerData{placed("a", 10, 10, 20)}, large, 10000, 400, 400, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeCoverage(tt.stickers, tt.device)
+
+			if math.Abs(got.SurfaceArea-tt.surfaceArea) > tt.surfaceArea*0.01 {
+				t.Errorf("SurfaceArea = %v, want %v", got.SurfaceArea, tt.surfaceArea)
+			}
+			if math.Abs(got.CoveredArea-tt.coveredArea) > 0.1+tt.coveredArea*0.01 {
+				t.Errorf("CoveredArea = %v, want %v", got.CoveredArea, tt.coveredArea)
+			}
+			if got.StickerArea != tt.stickerArea {
+				t.Errorf("StickerArea = %v, want %v", got.StickerArea, tt.stickerArea)
+			}
+			if len(got.Overlaps) != len(tt.overlapAreas) {
+				t.Fatalf("Overlaps = %v, want areas %v", got.Overlaps, tt.overlapAreas)
+			}
+			for i, area := range tt.overlapAreas {
+				if got.Overlaps[i].Area != area {
+					t.Errorf("overlap %d area = %v, want %v", i, got.Overlaps[i].Area, area)
+				}
+			}
+		})
+	}
+}
+
+func TestLegacyPixelsToInches(t *testing.T) {
+	tests := []struct {
+		name   string
+		pixels models.Position
+		inches models.Position
+	}{
+		{"origin", models.Position{}, models.Position{}},
+		{"full lid width", models.Position{X: 900, Y: 0}, models.Position{X: 12.3, Y: 0}},
+		{"inside the lid", models.Position{X: 450, Y: 219.5121951}, models.Position{X: 6.15, Y: 3}},
+		{"negative", models.Position{X: -90, Y: -180}, models.Position{X: -1.23, Y: -2.46}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.SaveSessionRequest{
+				Stickers: []models.SavedStickerData{{Position: tt.pixels}},
+				Surfaces: []models.SessionSurface{
+					{Stickers: []models.SavedStickerData{{Position: tt.pixels}}},
+					{Stickers: []models.SavedStickerData{{Position: tt.pixels}}},
+				},
+			}
+			LegacyPixelsToInches(req)
+
+			positions := []models.Position{req.Stickers[0].Position, req.Surfaces[0].Stickers[0].Position, req.Surfaces[1].Stickers[0].Position}
+			for _, got := range positions {
+				if !closePosition(got, tt.inches) {
+					t.Errorf("LegacyPixelsToInches() = %v, want %v", got, tt.inches)
+				}
+			}
+		})
+	}
+}
+
+func TestInchesToLegacyPixels(t *testing.T) {
+	first := []models.SavedStickerData{{Position: models.Position{X: 12.3, Y: 3}}}
+	resp := &models.GetSessionDataResponse{
+		Surfaces: []models.SessionSurface{
+			{Stickers: first},
+			{Stickers: []models.SavedStickerData{{Position: models.Position{X: -1.23}}}},
+		},
+		// Shares its backing array with the first surface, as GetSession
+		// builds it, and must be converted exactly once.
+		Stickers: first,
+	}
+	InchesToLegacyPixels(resp)
+
+	tests := []struct {
+		name string
+		got  models.Position
+		want models.Position
+	}{
+		{"first surface", resp.Surfaces[0].Stickers[0].Position, models.Position{X: 900, Y: 219.5121951}},
+		{"second surface", resp.Surfaces[1].Stickers[0].Position, models.Position{X: -90}},
+		{"legacy stickers", resp.Stickers[0].Position, models.Position{X: 900, Y: 219.5121951}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !closePosition(tt.got, tt.want) {
+				t.Errorf("position = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyPixelsRoundTrip(t *testing.T) {
+	position := models.Position{X: 123.45, Y: 678.9}
+	req := &models.SaveSessionRequest{Surfaces: []models.SessionSurface{{Stickers: []models.SavedStickerData{{Position: position}}}}}
+	LegacyPixelsToInches(req)
+
+	resp := &models.GetSessionDataResponse{Surfaces: req.Surfaces}
+	InchesToLegacyPixels(resp)
+
+	if got := resp.Surfaces[0].Stickers[0].Position; !closePosition(got, position) {
+		t.Errorf("round trip = %v, want %v", got, position)
+	}
+}
+
+func closePosition(a, b models.Position) bool {
+	return math.Abs(a.X-b.X) < 1e-6 && math.Abs(a.Y-b.Y) < 1e-6
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"server/internal/db"
+	"server/internal/models"
+	"server/internal/utils"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxImageBytes = 20 << 20
+
+// maxImagePixels caps the area of images we decode. A small compressed file
+// can declare enormous dimensions, and decoding allocates for every pixel.
+const maxImagePixels = 50_000_000
+
+// imageClient is the only client used to download images. It refuses to
+// connect to loopback, private and other non-public addresses, including after
+// redirects, so user-supplied image URLs cannot reach internal services.
+var imageClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return ErrBlockedImageAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// Widths of the derived images, keyed by the name used in responses. These are
+// the only widths GET /images/{hash}?w= accepts.
+var ImageDerivativeWidths = map[string]int{
+	"thumbnail": 160,
+	"medium":    640,
+	"print":     2400,
+}
+
+var (
+	ErrImageNotFound       = errors.New("image not found")
+	ErrInvalidImageWidth   = errors.New("image width not allowed")
+	ErrBlockedImageAddress = errors.New("image host resolves to a non-public address")
+	ErrNotAnImage          = errors.New("url does not point to a supported image")
+	ErrImageTooLarge       = errors.New("image dimensions are too large")
+	ErrImageFileTooLarge   = fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	errDatabaseUnavailable = errors.New("database unavailable")
+)
+
+func fetchImage(url string) ([]byte, error) {
+	resp, err := imageClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching image: %d", resp.StatusCode)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return nil, ErrNotAnImage
+	}
+
+	return readImageData(resp.Body)
+}
+
+// readImageData reads downloaded or uploaded image data up to maxImageBytes.
+func readImageData(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, ErrImageFileTooLarge
+	}
+	return data, nil
+}
+
+// decodeImage decodes untrusted image data, reading only the header first so
+// images over maxImagePixels are rejected before their pixels are allocated.
+func decodeImage(data []byte) (image.Image, string, error) {
+	width, height, err := utils.ImageDimensions(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if width <= 0 || height <= 0 || int64(width)*int64(height) > maxImagePixels {
+		return nil, "", ErrImageTooLarge
+	}
+	return utils.DecodeImage(data)
+}
+
+// sourceImage is downloaded image data with its decoded pixels and palette,
+// so checking, hashing and mirroring a sticker image share a single decode.
+type sourceImage struct {
+	data    []byte
+	decoded image.Image
+	format  string
+	palette []models.PaletteColor
+}
+
+// decodeSourceImage decodes downloaded image data within maxImagePixels.
+func decodeSourceImage(data []byte) (*sourceImage, error) {
+	decoded, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	palette := toPaletteColors(utils.DominantColors(decoded, stickerPaletteSize))
+	return &sourceImage{data: data, decoded: decoded, format: format, palette: palette}, nil
+}
+
+func imageURLs(hash string) map[string]string {
+	urls := make(map[string]string, len(ImageDerivativeWidths))
+	for name, width := range ImageDerivativeWidths {
+		urls[name] = fmt.Sprintf("/images/%s?w=%d", hash, width)
+	}
+	return urls
+}
+
+func isAllowedImageWidth(width int) bool {
+	for _, w := range ImageDerivativeWidths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+type ImageService struct {
+	dbClient *db.Client
+}
+
+func NewImageService(dbClient *db.Client) *ImageService {
+	return &ImageService{
+		dbClient: dbClient,
+	}
+}
+
+// MirrorImage stores the image and its palette under its content hash and
+// generates every derivative width, returning the hash.
+func (s *ImageService) MirrorImage(ctx context.Context, img *sourceImage, sourceURL string) (string, error) {
+	if s.dbClient == nil {
+		return "", errDatabaseUnavailable
+	}
+
+	sum := sha256.Sum256(img.data)
+	hash := hex.EncodeToString(sum[:])
+	bounds := img.decoded.Bounds()
+
+	err := s.dbClient.SaveImage(ctx, &models.Image{
+		Hash:        hash,
+		ContentType: http.DetectContentType(img.data),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Data:        img.data,
+		SourceURL:   sourceURL,
+		Palette:     img.palette,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, width := range ImageDerivativeWidths {
+		if width >= bounds.Dx() {
+			continue
+		}
+
+		derived, contentType, err := utils.EncodeImage(utils.ResizeToWidth(img.decoded, width), img.format)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %dpx derivative: %v", width, err)
+		}
+
+		if err := s.dbClient.SaveImageDerivative(ctx, hash, width, contentType, derived); err != nil {
+			return "", err
+		}
+	}
+
+	return hash, nil
+}
+
+// GetImage returns the content type and bytes of the image at the requested
+// width. A width of 0 returns the original. Images narrower than the requested
+// width are returned unscaled.
+func (s *ImageService) GetImage(ctx context.Context, hash string, width int) (string, []byte, error) {
+	if s.dbClient == nil {
+		return "", nil, errDatabaseUnavailable
+	}
+
+	if width != 0 && !isAllowedImageWidth(width) {
+		return "", nil, ErrInvalidImageWidth
+	}
+
+	if width != 0 {
+		contentType, data, err := s.dbClient.GetImageDerivative(ctx, hash, width)
+		if err == nil {
+			return contentType, data, nil
+		}
+		if !errors.Is(err, db.ErrImageNotFound) {
+			return "", nil, err
+		}
+	}
+
+	img, err := s.dbClient.GetImage(ctx, hash)
+	if errors.Is(err, db.ErrImageNotFound) {
+		return "", nil, ErrImageNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if width == 0 || width >= img.Width {
+		return img.ContentType, img.Data, nil
+	}
+
+	// The derivative is missing, e.g. because the allowed widths changed
+	// after the image was mirrored. Generate and cache it now.
+	decoded, format, err := decodeImage(img.Data)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	derived, contentType, err := utils.EncodeImage(utils.ResizeToWidth(decoded, width), format)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode %dpx derivative: %v", width, err)
+	}
+
+	if err := s.dbClient.SaveImageDerivative(ctx, hash, width, contentType, derived); err != nil {
+		log.Printf("Failed to cache %dpx derivative of %s: %v", width, hash, err)
+	}
+
+	return contentType, derived, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"log"
+	"server/internal/db"
+	"server/internal/models"
+	"sync/atomic"
+	"time"
+)
+
+// SessionJanitor periodically hard-deletes sessions whose restore window has
+// passed, that expired, or that were abandoned without any sticker. Its
+// counters are served by Stats.
+type SessionJanitor struct {
+	dbClient *db.Client
+	config   RetentionConfig
+
+	// Totals since start. The goroutine running the janitor writes them
+	// and Stats reads them from request handlers.
+	purgedDeleted   atomic.Int64
+	purgedExpired   atomic.Int64
+	purgedAbandoned atomic.Int64
+	sweeps          atomic.Int64
+	errors          atomic.Int64
+	lastSweep       atomic.Int64
+}
+
+func NewSessionJanitor(dbClient *db.Client, config RetentionConfig) *SessionJanitor {
+	return &SessionJanitor{
+		dbClient: dbClient,
+		config:   config,
+	}
+}
+
+// Stats returns the janitor's totals since start.
+func (j *SessionJanitor) Stats() models.JanitorStats {
+	stats := models.JanitorStats{
+		PurgedDeleted:   j.purgedDeleted.Load(),
+		PurgedExpired:   j.purgedExpired.Load(),
+		PurgedAbandoned: j.purgedAbandoned.Load(),
+		Sweeps:          j.sweeps.Load(),
+		Errors:          j.errors.Load(),
+	}
+	if last := j.lastSweep.Load(); last != 0 {
+		t := time.Unix(last, 0)
+		stats.LastSweep = &t
+	}
+	return stats
+}
+
+// counter returns the total of sessions purged for a reason.
+func (j *SessionJanitor) counter(reason db.PurgeReason) *atomic.Int64 {
+	switch reason {
+	case db.PurgeDeleted:
+		return &j.purgedDeleted
+	case db.PurgeExpired:
+		return &j.purgedExpired
+	}
+	return &j.purgedAbandoned
+}
+
+// Run sweeps once right away and then every JanitorInterval until ctx is
+// cancelled.
+func (j *SessionJanitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.JanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		j.Sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep purges every kind of dead session in batches of JanitorBatch, so no
+// single statement holds locks on a large part of the table.
+func (j *SessionJanitor) Sweep(ctx context.Context) {
+	now := time.Now()
+	cutoffs := map[db.PurgeReason]time.Time{
+		db.PurgeDeleted: now.Add(-j.config.RestoreWindow),
+		db.PurgeExpired: now,
+	}
+	if j.config.AbandonedAfter > 0 {
+		cutoffs[db.PurgeAbandoned] = now.Add(-j.config.AbandonedAfter)
+	}
+
+	for _, reason := range []db.PurgeReason{db.PurgeDeleted, db.PurgeExpired, db.PurgeAbandoned} {
+		cutoff, ok := cutoffs[reason]
+		if !ok {
+			continue
+		}
+
+		purged, err := j.purge(ctx, reason, cutoff)
+		j.counter(reason).Add(purged)
+		if err != nil {
+			j.errors.Add(1)
+			log.Printf("Session janitor failed to purge %s sessions after removing %d: %v", reason, purged, err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Session janitor purged %d %s sessions", purged, reason)
+		}
+	}
+
+	j.sweeps.Add(1)
+	j.lastSweep.Store(now.Unix())
+}
+
+func (j *SessionJanitor) purge(ctx context.Context, reason db.PurgeReason, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		purged, err := j.dbClient.PurgeSessions(ctx, reason, cutoff, j.config.JanitorBatch)
+		total += purged
+		if err != nil || purged < int64(j.config.JanitorBatch) {
+			return total, err
+		}
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"server/internal/db"
+	"server/internal/models"
+	"server/internal/utils"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrLibraryItemNotFound = errors.New("library item not found")
+	ErrInvalidLibraryItem  = errors.New("library items need an http(s) or /images URL and a positive size")
+	ErrInvalidNotes        = errors.New("notes are too long")
+)
+
+const (
+	defaultLibraryPageSize = 50
+	maxLibraryPageSize     = 100
+	maxLibraryURLLength    = 2000
+	maxNotesLength         = 5000
+)
+
+// isLibraryURL accepts absolute http(s) URLs and, if allowImages is set, the
+// server's own /images paths, which is what sticker resolution returns for
+// mirrored images.
+func isLibraryURL(value string, allowImages bool) bool {
+	if value == "" || len(value) > maxLibraryURLLength {
+		return false
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	if allowImages && parsed.Scheme == "" && parsed.Host == "" {
+		return strings.HasPrefix(parsed.Path, "/images/")
+	}
+	return (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "" && parsed.User == nil
+}
+
+// imagesHash returns the hash of a server /images URL, or "" for other URLs.
+func imagesHash(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return ""
+	}
+	hash, _ := strings.CutPrefix(parsed.Path, "/images/")
+	if hash == parsed.Path || strings.Contains(hash, "/") {
+		return ""
+	}
+	return hash
+}
+
+func isValidStickerSize(size models.Size) bool {
+	return size.Width > 0 && size.Height > 0 && !math.IsInf(size.Width, 0) && !math.IsInf(size.Height, 0)
+}
+
+// normalizeLibraryText trims the title and notes and checks their lengths.
+func normalizeLibraryText(title, notes *string) error {
+	*title = strings.TrimSpace(*title)
+	if len(*title) > maxStickerTitleLength {
+		return ErrInvalidStickerTitle
+	}
+	*notes = strings.TrimSpace(*notes)
+	if len(*notes) > maxNotesLength {
+		return ErrInvalidNotes
+	}
+	return nil
+}
+
+// authorizeLibraryItem checks that the user may use the item with at least
+// the given workspace role. Personal items are only visible to their owner;
+// anyone else is told the item does not exist.
+func authorizeLibraryItem(ctx context.Context, dbClient *db.Client, item *models.LibraryItem, userId, min string) error {
+	if userId == "" {
+		return ErrNotLoggedIn
+	}
+	if item.WorkspaceId == "" {
+		if item.OwnerId != userId {
+			return ErrLibraryItemNotFound
+		}
+		return nil
+	}
+
+	err := requireWorkspaceRole(ctx, dbClient, item.WorkspaceId, userId, min)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		return ErrLibraryItemNotFound
+	}
+	return err
+}
+
+// getLibraryItem loads an item the user may use with at least the given
+// workspace role.
+func getLibraryItem(ctx context.Context, dbClient *db.Client, itemId int64, userId, min string) (*models.LibraryItem, error) {
+	if userId == "" {
+		return nil, ErrNotLoggedIn
+	}
+
+	item, err := dbClient.GetLibraryItem(ctx, itemId)
+	if errors.Is(err, db.ErrLibraryItemNotFound) {
+		return nil, ErrLibraryItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeLibraryItem(ctx, dbClient, item, userId, min); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+type LibraryService struct {
+	dbClient *db.Client
+}
+
+func NewLibraryService(dbClient *db.Client) *LibraryService {
+	return &LibraryService{
+		dbClient: dbClient,
+	}
+}
+
+// SaveItem saves a resolved sticker to the user's library or, if
+// req.WorkspaceId is set, to a workspace in which they are at least an editor.
+func (s *LibraryService) SaveItem(ctx context.Context, userId string, req *models.SaveLibraryItemRequest) (*models.LibraryItem, error) {
+	if userId == "" {
+		return nil, ErrNotLoggedIn
+	}
+	if req.WorkspaceId != "" {
+		if err := requireWorkspaceRole(ctx, s.dbClient, req.WorkspaceId, userId, models.WorkspaceRoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	if !isLibraryURL(req.URL, true) || !isValidStickerSize(req.Size) {
+		return nil, ErrInvalidLibraryItem
+	}
+	if req.SourceURL != "" && !isLibraryURL(req.SourceURL, false) {
+		return nil, ErrInvalidLibraryItem
+	}
+	switch req.Source {
+	case "":
+		req.Source = models.StickerSourceStickerMule
+	case models.StickerSourceStickerMule, models.StickerSourceExternal:
+	default:
+		return nil, ErrInvalidStickerSource
+	}
+	if err := normalizeLibraryText(&req.Title, &req.Notes); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.LibraryItem{
+		WorkspaceId: req.WorkspaceId,
+		CreatedBy:   userId,
+		Title:       req.Title,
+		Notes:       req.Notes,
+		Tags:        tags,
+		URL:         req.URL,
+		SourceURL:   req.SourceURL,
+		Source:      req.Source,
+		Size:        req.Size,
+		ProductID:   req.ProductID,
+	}
+	if item.WorkspaceId == "" {
+		item.OwnerId = userId
+	}
+	if err := s.deriveImageFacts(ctx, item); err != nil {
+		return nil, err
+	}
+
+	if err := s.dbClient.CreateLibraryItem(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// deriveImageFacts fills in the image hash and palette of an item from what
+// the server stored itself rather than from the request: the catalog row of
+// item.ProductID, which must show the same image, or else the mirrored asset
+// of an /images URL. Items with other images get neither.
+func (s *LibraryService) deriveImageFacts(ctx context.Context, item *models.LibraryItem) error {
+	hash := imagesHash(item.URL)
+
+	if item.ProductID != 0 {
+		product, err := s.dbClient.GetProduct(ctx, item.ProductID)
+		if errors.Is(err, db.ErrProductNotFound) {
+			return ErrInvalidLibraryItem
+		}
+		if err != nil {
+			return err
+		}
+		if item.URL != product.ProductImage && (hash == "" || hash != product.ImageHash) {
+			return ErrInvalidLibraryItem
+		}
+		item.ImageHash, item.Palette = product.ImageHash, product.Palette
+		return nil
+	}
+
+	if hash == "" {
+		return nil
+	}
+	img, err := s.dbClient.GetImage(ctx, hash)
+	if errors.Is(err, db.ErrImageNotFound) {
+		return ErrInvalidLibraryItem
+	}
+	if err != nil {
+		return err
+	}
+	item.ImageHash = hash
+	item.Palette = img.Palette
+	if item.Palette != nil {
+		return nil
+	}
+
+	// Mirrored before palettes were stored with images.
+	decoded, _, err := decodeImage(img.Data)
+	if err != nil {
+		return err
+	}
+	item.Palette = toPaletteColors(utils.DominantColors(decoded, stickerPaletteSize))
+	return nil
+}
+
+// ListItems returns a page of the user's library, or of a workspace's they
+// belong to, newest first. The cursor is the id of the last item of the
+// previous page.
+func (s *LibraryService) ListItems(ctx context.Context, userId string, req *models.ListLibraryRequest) (*models.LibraryItemList, error) {
+	if userId == "" {
+		return nil, ErrNotLoggedIn
+	}
+	if req.WorkspaceId != "" {
+		if err := requireWorkspaceRole(ctx, s.dbClient, req.WorkspaceId, userId, models.WorkspaceRoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	query := db.LibraryQuery{
+		OwnerID:     userId,
+		WorkspaceID: req.WorkspaceId,
+		Text:        strings.TrimSpace(req.Query),
+		Limit:       req.Limit,
+	}
+	if len(query.Text) > maxSearchQueryLength {
+		return nil, ErrInvalidQuery
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultLibraryPageSize
+	case query.Limit < 0 || query.Limit > maxLibraryPageSize:
+		return nil, ErrInvalidLimit
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	query.Tags = tags
+
+	if req.Cursor != "" {
+		query.AfterID, err = strconv.ParseInt(req.Cursor, 10, 64)
+		if err != nil || query.AfterID < 1 {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := query.Limit
+	query.Limit++
+	items, err := s.dbClient.ListLibraryItems(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.LibraryItemList{Items: items}
+	if len(items) > limit {
+		list.Items = items[:limit]
+		list.NextCursor = strconv.FormatInt(list.Items[limit-1].Id, 10)
+	}
+	return list, nil
+}
+
+// GetItem returns an item from the user's library or a workspace's they
+// belong to.
+func (s *LibraryService) GetItem(ctx context.Context, userId string, itemId int64) (*models.LibraryItem, error) {
+	return getLibraryItem(ctx, s.dbClient, itemId, userId, models.WorkspaceRoleViewer)
+}
+
+// UpdateItem changes the title, notes or tags of an item. Workspace items
+// need the editor role.
+func (s *LibraryService) UpdateItem(ctx context.Context, userId string, itemId int64, req *models.UpdateLibraryItemRequest) (*models.LibraryItem, error) {
+	item, err := getLibraryItem(ctx, s.dbClient, itemId, userId, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		item.Title = *req.Title
+	}
+	if req.Notes != nil {
+		item.Notes = *req.Notes
+	}
+	if err := normalizeLibraryText(&item.Title, &item.Notes); err != nil {
+		return nil, err
+	}
+	if req.Tags != nil {
+		if item.Tags, err = normalizeTags(*req.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.dbClient.UpdateLibraryItem(ctx, item)
+	if errors.Is(err, db.ErrLibraryItemNotFound) {
+		return nil, ErrLibraryItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteItem removes an item. Sessions it was placed on keep their copy.
+func (s *LibraryService) DeleteItem(ctx context.Context, userId string, itemId int64) error {
+	if _, err := getLibraryItem(ctx, s.dbClient, itemId, userId, models.WorkspaceRoleEditor); err != nil {
+		return err
+	}
+
+	err := s.dbClient.DeleteLibraryItem(ctx, itemId)
+	if errors.Is(err, db.ErrLibraryItemNotFound) {
+		return ErrLibraryItemNotFound
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"server/internal/db"
+	"server/internal/models"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidExpiry        = errors.New("expiry must be in the future")
+	ErrSessionNotDeleted    = errors.New("session is not deleted")
+	ErrRestoreWindowExpired = errors.New("session was deleted too long ago to restore")
+)
+
+const (
+	defaultRestoreWindow   = 30 * 24 * time.Hour
+	defaultAbandonedAfter  = 90 * 24 * time.Hour
+	defaultJanitorInterval = time.Hour
+	defaultJanitorBatch    = 500
+)
+
+// RetentionConfig controls how long deleted sessions can be restored and
+// when the janitor purges sessions. A zero AbandonedAfter keeps empty
+// sessions forever. Only anonymous sessions, without an owner or a
+// workspace, are ever purged as abandoned.
+type RetentionConfig struct {
+	RestoreWindow   time.Duration
+	AbandonedAfter  time.Duration
+	JanitorInterval time.Duration
+	JanitorBatch    int
+}
+
+// NewRetentionConfig reads SESSION_RESTORE_WINDOW_DAYS,
+// SESSION_ABANDONED_AFTER_DAYS, SESSION_JANITOR_INTERVAL_MINUTES and
+// SESSION_JANITOR_BATCH_SIZE. By default deleted sessions can be restored for
+// 30 days and sessions nobody placed a sticker in are purged after 90 days of
+// inactivity.
+func NewRetentionConfig() RetentionConfig {
+	config := RetentionConfig{
+		RestoreWindow:   defaultRestoreWindow,
+		AbandonedAfter:  defaultAbandonedAfter,
+		JanitorInterval: defaultJanitorInterval,
+		JanitorBatch:    defaultJanitorBatch,
+	}
+
+	if value := os.Getenv("SESSION_RESTORE_WINDOW_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			log.Printf("Ignoring invalid SESSION_RESTORE_WINDOW_DAYS %q", value)
+		} else {
+			config.RestoreWindow = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	if value := os.Getenv("SESSION_ABANDONED_AFTER_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			log.Printf("Ignoring invalid SESSION_ABANDONED_AFTER_DAYS %q", value)
+		} else {
+			config.AbandonedAfter = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	if value := os.Getenv("SESSION_JANITOR_INTERVAL_MINUTES"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes <= 0 {
+			log.Printf("Ignoring invalid SESSION_JANITOR_INTERVAL_MINUTES %q", value)
+		} else {
+			config.JanitorInterval = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if value := os.Getenv("SESSION_JANITOR_BATCH_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			log.Printf("Ignoring invalid SESSION_JANITOR_BATCH_SIZE %q", value)
+		} else {
+			config.JanitorBatch = size
+		}
+	}
+
+	return config
+}
+
+// isLive reports whether the session is neither deleted nor expired.
+func isLive(access *models.SessionAccess, now time.Time) bool {
+	return access.DeletedAt == nil && (access.ExpiresAt == nil || access.ExpiresAt.After(now))
+}
+
+func validateExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+// DeleteSession soft-deletes a session. It can be restored within the
+// restore window, after which the janitor removes it for good.
+func (s *SessionService) DeleteSession(ctx context.Context, sessionId string) error {
+	if !s.isWellFormedSessionId(sessionId) {
+		return ErrInvalidSessionId
+	}
+
+	err := s.dbClient.SoftDeleteSession(ctx, sessionId)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// RestoreSession undoes a soft delete. The credentials must grant edit access
+// as they would have before the session was deleted.
+func (s *SessionService) RestoreSession(ctx context.Context, sessionId string, creds Credentials) error {
+	access, err := s.checkAccess(ctx, sessionId, creds, AccessEdit)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch {
+	case access.ExpiresAt != nil && !access.ExpiresAt.After(now):
+		return ErrSessionNotFound
+	case access.DeletedAt == nil:
+		return ErrSessionNotDeleted
+	}
+
+	deletedAfter := now.Add(-s.retentionConfig.RestoreWindow)
+	if !access.DeletedAt.After(deletedAfter) {
+		return ErrRestoreWindowExpired
+	}
+
+	err = s.dbClient.RestoreSession(ctx, sessionId, deletedAfter)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// SetExpiry sets when a session expires; nil removes the expiry. Expired
+// sessions are treated as missing and purged by the janitor.
+func (s *SessionService) SetExpiry(ctx context.Context, sessionId string, expiresAt *time.Time) error {
+	if !s.isWellFormedSessionId(sessionId) {
+		return ErrInvalidSessionId
+	}
+	if err := validateExpiry(expiresAt); err != nil {
+		return err
+	}
+
+	err := s.dbClient.SetSessionExpiry(ctx, sessionId, expiresAt)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"server/internal/models"
+	"server/internal/utils"
+	"sort"
+)
+
+const (
+	stickerPaletteSize = 5
+	sessionPaletteSize = 8
+)
+
+// Colors of different stickers closer than this (Euclidean RGB distance) are
+// merged when summarizing a session.
+const paletteMergeDistance = 32
+
+func toPaletteColors(colors []utils.WeightedColor) []models.PaletteColor {
+	palette := make([]models.PaletteColor, 0, len(colors))
+	for _, c := range colors {
+		palette = append(palette, models.PaletteColor{
+			Hex:    fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B),
+			Weight: math.Round(c.Weight*1000) / 1000,
+		})
+	}
+	return palette
+}
+
+func parseHex(hex string) (float64, float64, float64, bool) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return 0, 0, 0, false
+	}
+	return float64(r), float64(g), float64(b), true
+}
+
+type paletteBucket struct {
+	r, g, b float64
+	weight  float64
+}
+
+// summarizePalette combines sticker palettes into one, weighting each color by
+// the area of the sticker it belongs to. Similar colors are merged into a
+// weighted average and the heaviest colors are returned.
+func summarizePalette(stickers []models.StickerPalette) []models.PaletteColor {
+	var buckets []paletteBucket
+	var total float64
+	for _, sticker := range stickers {
+		area := sticker.Size.Width * sticker.Size.Height
+		for _, c := range sticker.Palette {
+			r, g, b, ok := parseHex(c.Hex)
+			if !ok {
+				continue
+			}
+			buckets = append(buckets, paletteBucket{r, g, b, c.Weight * area})
+			total += c.Weight * area
+		}
+	}
+	if total == 0 {
+		return []models.PaletteColor{}
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].weight > buckets[j].weight
+	})
+
+	var merged []paletteBucket
+	for _, bucket := range buckets {
+		found := false
+		for i := range merged {
+			m := &merged[i]
+			if math.Sqrt((m.r-bucket.r)*(m.r-bucket.r)+(m.g-bucket.g)*(m.g-bucket.g)+(m.b-bucket.b)*(m.b-bucket.b)) > paletteMergeDistance {
+				continue
+			}
+			w := m.weight + bucket.weight
+			m.r = (m.r*m.weight + bucket.r*bucket.weight) / w
+			m.g = (m.g*m.weight + bucket.g*bucket.weight) / w
+			m.b = (m.b*m.weight + bucket.b*bucket.weight) / w
+			m.weight = w
+			found = true
+			break
+		}
+		if !found {
+			merged = append(merged, bucket)
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].weight > merged[j].weight
+	})
+	if len(merged) > sessionPaletteSize {
+		merged = merged[:sessionPaletteSize]
+	}
+
+	colors := make([]utils.WeightedColor, 0, len(merged))
+	for _, m := range merged {
+		colors = append(colors, utils.WeightedColor{
+			R:      uint8(math.Round(m.r)),
+			G:      uint8(math.Round(m.g)),
+			B:      uint8(math.Round(m.b)),
+			Weight: m.weight / total,
+		})
+	}
+	return toPaletteColors(colors)
+}
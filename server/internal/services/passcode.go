@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"server/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrSessionLocked       = errors.New("session is locked with a passcode")
+	ErrWrongPasscode       = errors.New("wrong passcode")
+	ErrInvalidPasscode     = errors.New("passcode must be 4-72 characters")
+	ErrNoPasscode          = errors.New("session has no passcode")
+	ErrSessionNotProtected = errors.New("session has no tokens yet")
+)
+
+// RetryError is returned once too many wrong passcodes were tried.
+type RetryError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+const (
+	minPasscodeLength = 4
+	// bcrypt ignores everything past 72 bytes.
+	maxPasscodeLength = 72
+	passcodeCost      = 12
+
+	defaultUnlockTTL = 30 * time.Minute
+
+	// Failed unlock attempts allowed per window, for a session from one IP
+	// and for an IP across all sessions. The session limit is per IP so that
+	// one view link holder guessing wrong cannot lock out everyone else; the
+	// price is that guessers spread over many addresses get 5 tries per
+	// address. Passcodes should be long enough for that not to matter.
+	passcodeAttemptWindow     = 15 * time.Minute
+	maxPasscodeFailuresPerKey = 5
+	maxPasscodeFailuresPerIP  = 20
+)
+
+// Credentials are what a request presents to access a session. UserId is
+// the logged-in user, if any.
+type Credentials struct {
+	Token       string
+	UnlockToken string
+	UserId      string
+}
+
+type UnlockConfig struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+// NewUnlockConfig reads SESSION_UNLOCK_SECRET, used to sign unlock tokens, and
+// SESSION_UNLOCK_TTL_MINUTES. Without a secret a random one is used, so
+// unlocks do not survive restarts and are not shared between instances.
+func NewUnlockConfig() UnlockConfig {
+	config := UnlockConfig{TTL: defaultUnlockTTL}
+
+	if value := os.Getenv("SESSION_UNLOCK_SECRET"); value != "" {
+		config.Secret = []byte(value)
+	} else {
+		log.Println("SESSION_UNLOCK_SECRET is not set; using a random secret for this process")
+		config.Secret = make([]byte, 32)
+		if _, err := rand.Read(config.Secret); err != nil {
+			log.Fatalf("Failed to generate unlock secret: %v", err)
+		}
+	}
+
+	if value := os.Getenv("SESSION_UNLOCK_TTL_MINUTES"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 1 {
+			log.Printf("Ignoring invalid SESSION_UNLOCK_TTL_MINUTES %q", value)
+		} else {
+			config.TTL = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	return config
+}
+
+// signUnlock signs the session and expiry together with the passcode hash,
+// so changing the passcode invalidates earlier unlocks.
+func (s *SessionService) signUnlock(payload string, passcodeHash []byte) []byte {
+	mac := hmac.New(sha256.New, s.unlockConfig.Secret)
+	mac.Write([]byte(payload))
+	mac.Write(passcodeHash)
+	return mac.Sum(nil)
+}
+
+func (s *SessionService) issueUnlockToken(sessionId string, passcodeHash []byte, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(s.unlockConfig.TTL)
+	payload := sessionId + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	sig := s.signUnlock(payload, passcodeHash)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(sig), expiresAt
+}
+
+func (s *SessionService) validUnlockToken(sessionId string, passcodeHash []byte, token string, now time.Time) bool {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, s.signUnlock(string(payload), passcodeHash)) {
+		return false
+	}
+
+	id, expiry, ok := strings.Cut(string(payload), ".")
+	if !ok || id != sessionId {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	return err == nil && now.Unix() < expiresAt
+}
+
+// SetPasscode locks the session behind a passcode. The caller must already
+// have edit access, and the session must have tokens, otherwise anyone could
+// lock everyone else out. Owned sessions need their owner or the owner of
+// their workspace.
+func (s *SessionService) SetPasscode(ctx context.Context, sessionId, passcode, userId string) error {
+	if len(passcode) < minPasscodeLength || len(passcode) > maxPasscodeLength {
+		return ErrInvalidPasscode
+	}
+
+	access, err := s.dbClient.GetSessionAccess(ctx, sessionId, userId)
+	if err != nil {
+		return translateTokenError(err)
+	}
+	if err := checkSessionOwner(access, userId); err != nil {
+		return err
+	}
+	if !access.Protected {
+		return ErrSessionNotProtected
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(passcode), passcodeCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash passcode: %w", err)
+	}
+	return translateTokenError(s.dbClient.SetSessionPasscode(ctx, sessionId, hash))
+}
+
+// RemovePasscode unlocks the session for everyone with a token. Owned
+// sessions need their owner or the owner of their workspace.
+func (s *SessionService) RemovePasscode(ctx context.Context, sessionId, userId string) error {
+	if err := s.requireSessionOwner(ctx, sessionId, userId); err != nil {
+		return err
+	}
+	return translateTokenError(s.dbClient.SetSessionPasscode(ctx, sessionId, nil))
+}
+
+// Unlock checks the passcode and returns a signed unlock token. Callers need
+// at least view access. Each attempt counts against both the session from the
+// client IP and the client IP alone before the passcode is checked and is
+// refunded if it is right, so further attempts are refused with a RetryError
+// once either limit is reached, even when they arrive concurrently.
+func (s *SessionService) Unlock(ctx context.Context, sessionId string, creds Credentials, passcode, ip string) (*models.UnlockSessionResponse, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	access, err := s.dbClient.GetSessionAccess(ctx, sessionId, creds.UserId)
+	if err != nil {
+		return nil, translateTokenError(err)
+	}
+	if accessLevel(access, creds) < AccessView {
+		return nil, ErrAccessDenied
+	}
+	if access.PasscodeHash == nil {
+		return nil, ErrNoPasscode
+	}
+
+	now := time.Now()
+	sessionKey, ipKey := "session:"+sessionId+"@"+ip, "ip:"+ip
+	if wait := s.sessionAttempts.reserve(sessionKey, now); wait > 0 {
+		return nil, &RetryError{RetryAfter: wait}
+	}
+	if wait := s.ipAttempts.reserve(ipKey, now); wait > 0 {
+		s.sessionAttempts.refund(sessionKey)
+		return nil, &RetryError{RetryAfter: wait}
+	}
+
+	// The attempt is already counted; only a correct passcode gives it back.
+	if err := bcrypt.CompareHashAndPassword(access.PasscodeHash, []byte(passcode)); err != nil {
+		log.Printf("Failed unlock attempt for session %s from %s", sessionId, ip)
+		return nil, ErrWrongPasscode
+	}
+
+	s.sessionAttempts.refund(sessionKey)
+	s.ipAttempts.refund(ipKey)
+
+	token, expiresAt := s.issueUnlockToken(sessionId, access.PasscodeHash, now)
+	return &models.UnlockSessionResponse{UnlockToken: token, ExpiresAt: expiresAt}, nil
+}
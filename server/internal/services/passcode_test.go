@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidUnlockToken(t *testing.T) {
+	s := &SessionService{unlockConfig: UnlockConfig{Secret: []byte("secret"), TTL: 30 * time.Minute}}
+	other := &SessionService{unlockConfig: UnlockConfig{Secret: []byte("other secret"), TTL: 30 * time.Minute}}
+	hash := []byte("passcode hash")
+	now := time.Unix(1_700_000_000, 0)
+
+	token, expiresAt := s.issueUnlockToken("aB3dE5gH", hash, now)
+	if want := now.Add(30 * time.Minute); !expiresAt.Equal(want) {
+		t.Errorf("issueUnlockToken() expires at %v, want %v", expiresAt, want)
+	}
+
+	payload, sig, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte("aB3dE5gH.9999999999")) + "." + sig
+	foreign, _ := other.issueUnlockToken("aB3dE5gH", hash, now)
+
+	tests := []struct {
+		name      string
+		sessionId string
+		hash      []byte
+		token     string
+		now       time.Time
+		want      bool
+	}{
+		{"valid", "aB3dE5gH", hash, token, now, true},
+		{"just before expiry", "aB3dE5gH", hash, token, expiresAt.Add(-time.Second), true},
+		{"expired", "aB3dE5gH", hash, token, expiresAt, false},
+		{"other session", "zZ9yY8xX", hash, token, now, false},
+		{"passcode changed", "aB3dE5gH", []byte("new passcode hash"), token, now, false},
+		{"signed with another secret", "aB3dE5gH", hash, foreign, now, false},
+		{"payload changed", "aB3dE5gH", hash, forged, now, false},
+		{"signature changed", "aB3dE5gH", hash, payload + "." + base64.RawURLEncoding.EncodeToString([]byte("signature")), now, false},
+		{"empty", "aB3dE5gH", hash, "", now, false},
+		{"no signature", "aB3dE5gH", hash, payload, now, false},
+		{"not base64", "aB3dE5gH", hash, "!!!." + sig, now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.validUnlockToken(tt.sessionId, tt.hash, tt.token, tt.now); got != tt.want {
+				t.Errorf("validUnlockToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
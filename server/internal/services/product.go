@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+)
+
+const (
+	DefaultDuplicateDistance = 10
+	MaxDuplicateDistance     = 32
+)
+
+var (
+	ErrProductNotFound  = errors.New("product not found")
+	ErrProductNotHashed = errors.New("product has no perceptual hash")
+)
+
+type ProductService struct {
+	dbClient *db.Client
+}
+
+func NewProductService(dbClient *db.Client) *ProductService {
+	return &ProductService{
+		dbClient: dbClient,
+	}
+}
+
+// RecordProduct adds or refreshes a scraped sticker in the product catalog and
+// returns its catalog ID.
+func (s *ProductService) RecordProduct(ctx context.Context, product *models.Product) (int64, error) {
+	if s.dbClient == nil {
+		return 0, errDatabaseUnavailable
+	}
+	return s.dbClient.UpsertProduct(ctx, product)
+}
+
+// FindNearDuplicates returns the product with every catalog entry whose
+// perceptual hash is within maxDistance bits of its own.
+func (s *ProductService) FindNearDuplicates(ctx context.Context, productID int64, maxDistance int) (*models.NearDuplicatesResponse, error) {
+	if s.dbClient == nil {
+		return nil, errDatabaseUnavailable
+	}
+
+	product, err := s.dbClient.GetProduct(ctx, productID)
+	if errors.Is(err, db.ErrProductNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if product.PerceptualHash == nil {
+		return nil, ErrProductNotHashed
+	}
+
+	duplicates, err := s.dbClient.FindNearDuplicates(ctx, product.ID, *product.PerceptualHash, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NearDuplicatesResponse{
+		Product:    *product,
+		Duplicates: duplicates,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Keys an attemptLimiter tracks at once. When every tracked key is still
+// inside its window, attempts for new keys are refused until the oldest window
+// ends: under a flood of distinct keys the limiter fails closed rather than
+// growing without bound or forgetting keys that are being guessed against.
+// That refuses legitimate attempts for new keys as well, so an attacker with
+// enough addresses can hold off unlocks and logins for everyone on this
+// instance for up to a window; the limit is high enough that this takes far
+// more traffic than ordinary use produces.
+const maxTrackedAttemptKeys = 10000
+
+// attemptLimiter counts attempts per key in fixed windows and blocks a key
+// once it reaches the limit, until its window ends. Attempts are reserved
+// before they are checked, so concurrent requests cannot all pass the limit,
+// and refunded if they succeed. State is per process.
+type attemptLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	entries map[string]*attemptWindow
+}
+
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:   limit,
+		window:  window,
+		entries: make(map[string]*attemptWindow),
+	}
+}
+
+// reserve counts an attempt for key and returns 0, or, if key is blocked or
+// no more keys can be tracked, returns how long to wait without counting it.
+func (l *attemptLimiter) reserve(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok || now.Sub(entry.start) >= l.window {
+		if !ok && len(l.entries) >= maxTrackedAttemptKeys {
+			if wait := l.sweep(now); wait > 0 {
+				return wait
+			}
+		}
+		entry = &attemptWindow{start: now}
+		l.entries[key] = entry
+	}
+	if entry.count >= l.limit {
+		return entry.start.Add(l.window).Sub(now)
+	}
+	entry.count++
+	return 0
+}
+
+// refund gives back an attempt reserved for key, for attempts that succeeded.
+func (l *attemptLimiter) refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		return
+	}
+	entry.count--
+	if entry.count <= 0 {
+		delete(l.entries, key)
+	}
+}
+
+// sweep removes the keys whose window has ended. If none has, it returns how
+// long until the first one does.
+func (l *attemptLimiter) sweep(now time.Time) time.Duration {
+	var wait time.Duration
+	for key, entry := range l.entries {
+		remaining := entry.start.Add(l.window).Sub(now)
+		switch {
+		case remaining <= 0:
+			delete(l.entries, key)
+		case wait == 0 || remaining < wait:
+			wait = remaining
+		}
+	}
+	if len(l.entries) < maxTrackedAttemptKeys {
+		return 0
+	}
+	return wait
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type step struct {
+		key    string
+		after  time.Duration
+		refund bool
+		want   time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "blocks at the limit until the window ends",
+			steps: []step{
+				{key: "a", want: 0},
+				{key: "a", after: time.Second, want: 0},
+				{key: "a", after: 2 * time.Second, want: 0},
+				{key: "a", after: 3 * time.Second, want: time.Minute - 3*time.Second},
+				{key: "a", after: time.Minute, want: 0},
+			},
+		},
+		{
+			name: "keys are counted separately",
+			steps: []step{
+				{key: "a"}, {key: "a"}, {key: "a"},
+				{key: "b", want: 0},
+			},
+		},
+		{
+			name: "refunded attempts do not count",
+			steps: []step{
+				{key: "a"}, {key: "a"},
+				{key: "a", refund: true},
+				{key: "a", want: 0},
+				{key: "a", want: 0},
+				{key: "a", want: time.Minute},
+			},
+		},
+		{
+			name: "blocked attempts are not counted",
+			steps: []step{
+				{key: "a"}, {key: "a"}, {key: "a"},
+				{key: "a", after: 30 * time.Second, want: 30 * time.Second},
+				{key: "a", after: 30 * time.Second, want: 30 * time.Second},
+				{key: "a", after: time.Minute, want: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newAttemptLimiter(3, time.Minute)
+			for i, s := range tt.steps {
+				if s.refund {
+					limiter.refund(s.key)
+					continue
+				}
+				if got := limiter.reserve(s.key, start.Add(s.after)); got != s.want {
+					t.Errorf("step %d: reserve(%q) = %v, want %v", i, s.key, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAttemptLimiterFull(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newAttemptLimiter(3, time.Minute)
+	for i := 0; i < maxTrackedAttemptKeys; i++ {
+		limiter.reserve(strconv.Itoa(i), start)
+	}
+
+	// With every key still in its window, new keys wait for the oldest one.
+	if got := limiter.reserve("new", start.Add(10*time.Second)); got != 50*time.Second {
+		t.Errorf("reserve(new) while full = %v, want 50s", got)
+	}
+	// Tracked keys still count.
+	if got := limiter.reserve("0", start.Add(10*time.Second)); got != 0 {
+		t.Errorf("reserve(0) while full = %v, want 0", got)
+	}
+	// Once the windows end, the old keys are swept to make room.
+	if got := limiter.reserve("new", start.Add(time.Minute)); got != 0 {
+		t.Errorf("reserve(new) after the window = %v, want 0", got)
+	}
+	if n := len(limiter.entries); n != 1 {
+		t.Errorf("%d keys tracked after the sweep, want 1", n)
+	}
+}
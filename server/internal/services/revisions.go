@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"server/internal/db"
+	"server/internal/models"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrHistoryPruned    = errors.New("history before this time is no longer kept")
+)
+
+const defaultRevisionLimit = 100
+
+// RevisionConfig bounds how many revisions each session keeps. Zero disables
+// a limit.
+type RevisionConfig struct {
+	Limit  int
+	MaxAge time.Duration
+}
+
+// NewRevisionConfig reads SESSION_REVISION_LIMIT and
+// SESSION_REVISION_MAX_AGE_DAYS, keeping the last 100 revisions of a session
+// regardless of age by default.
+func NewRevisionConfig() RevisionConfig {
+	config := RevisionConfig{Limit: defaultRevisionLimit}
+
+	if value := os.Getenv("SESSION_REVISION_LIMIT"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			log.Printf("Ignoring invalid SESSION_REVISION_LIMIT %q", value)
+		} else {
+			config.Limit = limit
+		}
+	}
+
+	if value := os.Getenv("SESSION_REVISION_MAX_AGE_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			log.Printf("Ignoring invalid SESSION_REVISION_MAX_AGE_DAYS %q", value)
+		} else {
+			config.MaxAge = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	return config
+}
+
+// pruneRevisions applies the retention policy after a write that recorded a
+// revision. The write has already succeeded, so failures are only logged.
+func (s *SessionService) pruneRevisions(ctx context.Context, sessionId string) {
+	if s.revisionConfig.Limit == 0 && s.revisionConfig.MaxAge == 0 {
+		return
+	}
+
+	if _, err := s.dbClient.PruneSessionRevisions(ctx, sessionId, s.revisionConfig.Limit, s.revisionConfig.MaxAge); err != nil {
+		log.Printf("Failed to prune revisions of session %s: %v", sessionId, err)
+	}
+}
+
+// ListRevisions returns the revisions of a session, newest first, without
+// their snapshots.
+func (s *SessionService) ListRevisions(ctx context.Context, sessionId string) ([]models.SessionRevision, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	revisions, err := s.dbClient.ListSessionRevisions(ctx, sessionId)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	return revisions, err
+}
+
+func (s *SessionService) GetRevision(ctx context.Context, sessionId string, version int) (*models.SessionRevision, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	revision, err := s.dbClient.GetSessionRevision(ctx, sessionId, version)
+	if errors.Is(err, db.ErrRevisionNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, err
+}
+
+// RestoreRevision saves the snapshot of an earlier revision as the new head.
+// The restore is itself a save, so it gets a new version and revision and the
+// revisions in between are kept.
+func (s *SessionService) RestoreRevision(ctx context.Context, sessionId string, version int, author string, expectedVersion int) (int, error) {
+	revision, err := s.GetRevision(ctx, sessionId, version)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshot := revision.Snapshot
+	return s.SaveSession(ctx, &models.SaveSessionRequest{
+		SessionId:   sessionId,
+		Surfaces:    snapshot.Surfaces,
+		Title:       &snapshot.Title,
+		Description: &snapshot.Description,
+		Settings:    snapshot.Settings,
+		Author:      author,
+	}, expectedVersion)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidOwner  = errors.New("owner must be me and cannot be combined with workspace")
+	ErrInvalidSort   = errors.New("sort must be updated, created or title")
+	ErrInvalidCursor = errors.New("malformed cursor")
+	ErrInvalidLimit  = errors.New("limit must be 1-100")
+	ErrInvalidQuery  = errors.New("search query is too long")
+)
+
+const (
+	defaultSessionPageSize = 20
+	maxSessionPageSize     = 100
+	maxSearchQueryLength   = 200
+)
+
+// sessionCursor is what a page cursor encodes. The sort is kept so a cursor
+// cannot be replayed against a different order.
+type sessionCursor struct {
+	Sort  db.SessionSort `json:"s"`
+	Time  time.Time      `json:"t"`
+	Title string         `json:"v,omitempty"`
+	Id    string         `json:"id"`
+}
+
+func encodeSessionCursor(sort db.SessionSort, session *models.Session) string {
+	cursor := sessionCursor{Sort: sort, Id: session.Id}
+	switch sort {
+	case db.SortUpdated:
+		cursor.Time = session.UpdatedAt
+	case db.SortCreated:
+		cursor.Time = session.CreatedAt
+	case db.SortTitle:
+		cursor.Title = session.Title
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSessionCursor(sort db.SessionSort, value string) (*db.SessionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor sessionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &db.SessionCursor{Time: cursor.Time, Title: cursor.Title, Id: cursor.Id}, nil
+}
+
+// normalizeTags lowercases, trims and de-duplicates tags, keeping their order.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, ErrInvalidTags
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength {
+			return nil, ErrInvalidTags
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// ListSessions returns a page of the user's live sessions, or of those of a
+// workspace they belong to, most recently updated first unless another sort
+// is asked for.
+func (s *SessionService) ListSessions(ctx context.Context, userId string, req *models.ListSessionsRequest) (*models.SessionList, error) {
+	if userId == "" {
+		return nil, ErrNotLoggedIn
+	}
+	if (req.Owner != "" && req.Owner != "me") || (req.Owner != "" && req.WorkspaceId != "") {
+		return nil, ErrInvalidOwner
+	}
+	if req.WorkspaceId != "" {
+		if err := requireWorkspaceRole(ctx, s.dbClient, req.WorkspaceId, userId, models.WorkspaceRoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	query := db.SessionQuery{
+		OwnerID:     userId,
+		WorkspaceID: req.WorkspaceId,
+		Text:        strings.TrimSpace(req.Query),
+		DeviceID:    req.DeviceId,
+		Sort:        db.SessionSort(req.Sort),
+		Limit:       req.Limit,
+	}
+	if len(query.Text) > maxSearchQueryLength {
+		return nil, ErrInvalidQuery
+	}
+
+	switch query.Sort {
+	case "":
+		query.Sort = db.SortUpdated
+	case db.SortUpdated, db.SortCreated, db.SortTitle:
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultSessionPageSize
+	case query.Limit < 0 || query.Limit > maxSessionPageSize:
+		return nil, ErrInvalidLimit
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	query.Tags = tags
+
+	if req.Cursor != "" {
+		if query.After, err = decodeSessionCursor(query.Sort, req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// One extra row tells whether there is a next page.
+	limit := query.Limit
+	query.Limit++
+	sessions, err := s.dbClient.ListSessions(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.SessionList{Sessions: sessions}
+	if len(sessions) > limit {
+		list.Sessions = sessions[:limit]
+		list.NextCursor = encodeSessionCursor(query.Sort, &list.Sessions[limit-1])
+	}
+	return list, nil
+}
+
+// MoveSession moves a session into a workspace in which the user is at least
+// an editor, or out of any workspace when workspaceId is empty. Edit access is
+// not enough: the user must own the session or be an owner of the workspace
+// it is in now, since moving it changes who can see it.
+func (s *SessionService) MoveSession(ctx context.Context, sessionId, userId, workspaceId string) error {
+	if !s.isWellFormedSessionId(sessionId) {
+		return ErrInvalidSessionId
+	}
+	if userId == "" {
+		return ErrNotLoggedIn
+	}
+
+	access, err := s.dbClient.GetSessionAccess(ctx, sessionId, userId)
+	if err != nil {
+		return translateTokenError(err)
+	}
+	if access.OwnerId != userId && access.WorkspaceRole != models.WorkspaceRoleOwner {
+		return ErrSessionOwnerRequired
+	}
+
+	if workspaceId != "" {
+		if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, models.WorkspaceRoleEditor); err != nil {
+			return err
+		}
+	}
+	return translateWorkspaceError(s.dbClient.SetSessionWorkspace(ctx, sessionId, workspaceId))
+}
+
+// SetTags replaces the tags of a session.
+func (s *SessionService) SetTags(ctx context.Context, sessionId string, tags []string) ([]string, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.dbClient.SetSessionTags(ctx, sessionId, tags)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	return tags, err
+}
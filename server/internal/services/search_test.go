@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/db"
+	"server/internal/models"
+)
+
+func TestSessionCursor(t *testing.T) {
+	session := &models.Session{
+		Id:        "abc123",
+		Title:     "Laptop",
+		CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2025, 2, 1, 12, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name string
+		sort db.SessionSort
+		want db.SessionCursor
+	}{
+		{"updated", db.SortUpdated, db.SessionCursor{Time: session.UpdatedAt, Id: "abc123"}},
+		{"created", db.SortCreated, db.SessionCursor{Time: session.CreatedAt, Id: "abc123"}},
+		{"title", db.SortTitle, db.SessionCursor{Title: "Laptop", Id: "abc123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeSessionCursor(tt.sort, encodeSessionCursor(tt.sort, session))
+			if err != nil {
+				t.Fatalf("decodeSessionCursor() error = %v", err)
+			}
+			if !cursor.Time.Equal(tt.want.Time) || cursor.Title != tt.want.Title || cursor.Id != tt.want.Id {
+				t.Errorf("decodeSessionCursor() = %+v, want %+v", *cursor, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSessionCursorInvalid(t *testing.T) {
+	session := &models.Session{Id: "abc123"}
+
+	tests := []struct {
+		name  string
+		sort  db.SessionSort
+		value string
+	}{
+		{"not base64", db.SortUpdated, "!!!"},
+		{"not JSON", db.SortUpdated, "bm90IGpzb24"},
+		{"other sort", db.SortTitle, encodeSessionCursor(db.SortUpdated, session)},
+		{"no id", db.SortUpdated, encodeSessionCursor(db.SortUpdated, &models.Session{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeSessionCursor(tt.sort, tt.value); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeSessionCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tooMany := make([]string, maxTags+1)
+	for i := range tooMany {
+		tooMany[i] = "tag"
+	}
+
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{"none", nil, []string{}, false},
+		{"lowercased and trimmed", []string{" Work ", "MAC"}, []string{"work", "mac"}, false},
+		{"duplicates dropped in order", []string{"b", "a", "B", "a"}, []string{"b", "a"}, false},
+		{"empty tag", []string{"a", "  "}, nil, true},
+		{"tag too long", []string{strings.Repeat("x", maxTagLength+1)}, nil, true},
+		{"too many tags", tooMany, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.tags)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTags) {
+					t.Errorf("normalizeTags() error = %v, want ErrInvalidTags", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeTags() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
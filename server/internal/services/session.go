@@ -2,24 +2,380 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"server/internal/db"
 	"server/internal/models"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidStickerSource = errors.New("invalid sticker source")
+	ErrInvalidTransform     = errors.New("invalid sticker transform")
+	ErrInvalidSurface       = errors.New("surface ids must be unique and 1-64 characters")
+	ErrSurfaceNotFound      = errors.New("surface not found")
+	ErrInvalidSessionId     = errors.New("malformed session id")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrInvalidSessionTitle  = errors.New("session title is too long")
+	ErrVersionConflict      = errors.New("session was modified since it was loaded")
+	ErrDuplicateStickerId   = errors.New("sticker ids must be unique within a surface")
+	ErrInvalidStickerTitle  = errors.New("sticker title is too long")
+	ErrInvalidTags          = errors.New("tags must be 1-50 characters and at most 20")
+	ErrTooManySurfaces      = errors.New("a session has at most 10 surfaces")
+	ErrTooManyStickers      = errors.New("a surface has at most 200 stickers")
+)
+
+// Attempts at generating an unused session ID before giving up.
+const maxSessionIdAttempts = 5
+
+const (
+	maxSessionTitleLength = 255
+	maxStickerTitleLength = 255
+	maxTagLength          = 50
+	maxTags               = 20
+)
+
+const (
+	minStickerScale = 0.1
+	maxStickerScale = 10
+)
+
+const (
+	// defaultSurfaceId names the surface sessions from before surfaces
+	// existed were migrated to.
+	defaultSurfaceId   = "default"
+	maxSurfaceIdLength = 64
+	maxSurfaces        = 10
 )
 
 type SessionService struct {
-	dbClient *db.Client
+	dbClient        *db.Client
+	deviceService   *DeviceService
+	idConfig        SessionIDConfig
+	revisionConfig  RevisionConfig
+	unlockConfig    UnlockConfig
+	retentionConfig RetentionConfig
+	sessionAttempts *attemptLimiter
+	ipAttempts      *attemptLimiter
 }
 
-func NewSessionService(dbClient *db.Client) *SessionService {
+func NewSessionService(dbClient *db.Client, deviceService *DeviceService, idConfig SessionIDConfig, revisionConfig RevisionConfig, unlockConfig UnlockConfig, retentionConfig RetentionConfig) *SessionService {
 	return &SessionService{
-		dbClient: dbClient,
+		dbClient:        dbClient,
+		deviceService:   deviceService,
+		idConfig:        idConfig,
+		revisionConfig:  revisionConfig,
+		unlockConfig:    unlockConfig,
+		retentionConfig: retentionConfig,
+		sessionAttempts: newAttemptLimiter(maxPasscodeFailuresPerKey, passcodeAttemptWindow),
+		ipAttempts:      newAttemptLimiter(maxPasscodeFailuresPerIP, passcodeAttemptWindow),
+	}
+}
+
+// CreateSession creates an empty session under a new random ID together with
+// its view and edit tokens. A non-empty ownerId links it to that user, who
+// must be at least an editor of req.WorkspaceId if it is set. The primary key
+// guarantees uniqueness; on the rare collision a new ID is drawn.
+func (s *SessionService) CreateSession(ctx context.Context, req *models.CreateSessionRequest, ownerId string) (*models.SessionWithTokens, error) {
+	if len(req.Title) > maxSessionTitleLength {
+		return nil, ErrInvalidSessionTitle
+	}
+	if err := validateExpiry(req.ExpiresAt); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if req.WorkspaceId != "" {
+		if err := requireWorkspaceRole(ctx, s.dbClient, req.WorkspaceId, ownerId, models.WorkspaceRoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	deviceId := req.DeviceId
+	if deviceId == "" {
+		deviceId = DefaultDeviceID
+	}
+	if err := s.deviceService.ValidateSelection(ctx, deviceId, ""); err != nil {
+		return nil, err
+	}
+
+	tokens, access, err := newSessionTokens()
+	if err != nil {
+		return nil, err
+	}
+	access.OwnerId = ownerId
+
+	for attempt := 0; attempt < maxSessionIdAttempts; attempt++ {
+		id, err := generateSessionId(s.idConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		session := &models.Session{
+			Id:          id,
+			Title:       req.Title,
+			Description: req.Description,
+			OwnerId:     ownerId,
+			WorkspaceId: req.WorkspaceId,
+			DeviceId:    deviceId,
+			Tags:        tags,
+			ExpiresAt:   req.ExpiresAt,
+		}
+		err = s.dbClient.CreateSession(ctx, session, access)
+		if errors.Is(err, db.ErrSessionExists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &models.SessionWithTokens{Session: session, Tokens: tokens}, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate an unused session id after %d attempts", maxSessionIdAttempts)
+}
+
+// SaveSession stores the session, records it as a revision and returns its
+// new version. When expectedVersion is non-zero the save is rejected with
+// ErrVersionConflict if someone else saved in the meantime; 0 overwrites
+// unconditionally.
+func (s *SessionService) SaveSession(ctx context.Context, req *models.SaveSessionRequest, expectedVersion int) (int, error) {
+	if !s.isWellFormedSessionId(req.SessionId) {
+		return 0, ErrInvalidSessionId
+	}
+	if req.Title != nil && len(*req.Title) > maxSessionTitleLength {
+		return 0, ErrInvalidSessionTitle
+	}
+
+	if len(req.Surfaces) == 0 {
+		surfaces, err := s.surfacesFromLegacyRequest(ctx, req)
+		if err != nil {
+			return 0, err
+		}
+		req.Surfaces = surfaces
+	}
+
+	if len(req.Surfaces) > maxSurfaces {
+		return 0, ErrTooManySurfaces
+	}
+	seen := make(map[string]bool, len(req.Surfaces))
+	for i := range req.Surfaces {
+		surface := &req.Surfaces[i]
+		if surface.Id == "" || len(surface.Id) > maxSurfaceIdLength || seen[surface.Id] {
+			return 0, ErrInvalidSurface
+		}
+		seen[surface.Id] = true
+
+		if err := s.normalizeSurface(ctx, surface); err != nil {
+			return 0, err
+		}
+	}
+
+	version, err := s.dbClient.SaveSession(ctx, req, expectedVersion)
+	switch {
+	case errors.Is(err, db.ErrSessionNotFound):
+		return 0, ErrSessionNotFound
+	case errors.Is(err, db.ErrVersionConflict):
+		return 0, ErrVersionConflict
+	case err != nil:
+		return 0, err
+	}
+
+	s.pruneRevisions(ctx, req.SessionId)
+	return version, nil
+}
+
+// surfacesFromLegacyRequest applies a request from a client that predates
+// surfaces to the first stored surface, leaving any others untouched.
+func (s *SessionService) surfacesFromLegacyRequest(ctx context.Context, req *models.SaveSessionRequest) ([]models.SessionSurface, error) {
+	existing, err := s.dbClient.GetSession(ctx, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	surfaces := existing.Surfaces
+	if len(surfaces) == 0 {
+		surfaces = []models.SessionSurface{{Id: defaultSurfaceId, DeviceId: DefaultDeviceID}}
+	}
+
+	surfaces[0].Stickers = req.Stickers
+	if req.DeviceId != "" {
+		surfaces[0].DeviceId = req.DeviceId
+		surfaces[0].DeviceVariant = req.DeviceVariant
+	}
+
+	return surfaces, nil
+}
+
+// normalizeSurface validates the device and stickers of a surface and fills
+// in defaults, naming unnamed surfaces after their device.
+func (s *SessionService) normalizeSurface(ctx context.Context, surface *models.SessionSurface) error {
+	if surface.DeviceId == "" {
+		surface.DeviceId = DefaultDeviceID
+	}
+	if err := s.deviceService.ValidateSelection(ctx, surface.DeviceId, surface.DeviceVariant); err != nil {
+		return err
+	}
+	if surface.Name == "" {
+		device, err := s.deviceService.GetDevice(ctx, surface.DeviceId)
+		if err != nil {
+			return err
+		}
+		surface.Name = device.Name
+	}
+
+	if len(surface.Stickers) > db.MaxStickersPerSurface {
+		return ErrTooManyStickers
 	}
+	seen := make(map[string]bool, len(surface.Stickers))
+	for i := range surface.Stickers {
+		sticker := &surface.Stickers[i]
+		if seen[sticker.StickerId] {
+			return ErrDuplicateStickerId
+		}
+		seen[sticker.StickerId] = true
+
+		if err := normalizeSticker(sticker); err != nil {
+			return err
+		}
+	}
+
+	normalizeZIndices(surface.Stickers)
+
+	return nil
 }
 
-func (s *SessionService) SaveSession(ctx context.Context, req *models.SaveSessionRequest) error {
-	return s.dbClient.SaveSession(ctx, req)
+// normalizeSticker validates the source and transform of a placement and
+// fills in their defaults.
+func normalizeSticker(sticker *models.SavedStickerData) error {
+	switch sticker.Source {
+	case "":
+		// Stickers saved before external images existed are Sticker Mule products.
+		sticker.Source = models.StickerSourceStickerMule
+	case models.StickerSourceStickerMule, models.StickerSourceExternal:
+	default:
+		return ErrInvalidStickerSource
+	}
+
+	sticker.Title = strings.TrimSpace(sticker.Title)
+	if len(sticker.Title) > maxStickerTitleLength {
+		return ErrInvalidStickerTitle
+	}
+
+	return normalizeTransform(&sticker.Transform)
+}
+
+// normalizeTransform fills in defaults for clients that send no transform,
+// checks ranges and wraps the rotation into [0, 360). A zero scale or opacity
+// means "not set" and becomes 1.
+func normalizeTransform(t *models.Transform) error {
+	if t.Scale == 0 {
+		t.Scale = 1
+	}
+	if t.Opacity == 0 {
+		t.Opacity = 1
+	}
+
+	if math.IsNaN(t.Rotation) || math.IsInf(t.Rotation, 0) {
+		return ErrInvalidTransform
+	}
+	if !(t.Scale >= minStickerScale && t.Scale <= maxStickerScale) {
+		return ErrInvalidTransform
+	}
+	if !(t.Opacity > 0 && t.Opacity <= 1) {
+		return ErrInvalidTransform
+	}
+
+	t.Rotation = math.Mod(t.Rotation, 360)
+	if t.Rotation < 0 {
+		t.Rotation += 360
+	}
+
+	return nil
+}
+
+// normalizeZIndices sorts stickers by layer and renumbers them 0..n-1 from the
+// bottom up. Ties, including clients that send no zIndex at all, keep their
+// order in the request.
+func normalizeZIndices(stickers []models.SavedStickerData) {
+	sort.SliceStable(stickers, func(i, j int) bool {
+		return stickers[i].ZIndex < stickers[j].ZIndex
+	})
+	for i := range stickers {
+		stickers[i].ZIndex = i
+	}
 }
 
 func (s *SessionService) GetSession(ctx context.Context, sessionId string) (*models.GetSessionDataResponse, error) {
-	return s.dbClient.GetSession(ctx, sessionId)
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	session, err := s.dbClient.GetSession(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if session.Session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	// Mirror the first surface for clients that predate surfaces.
+	session.DeviceId = DefaultDeviceID
+	if len(session.Surfaces) > 0 {
+		session.Stickers = session.Surfaces[0].Stickers
+		session.DeviceId = session.Surfaces[0].DeviceId
+		session.DeviceVariant = session.Surfaces[0].DeviceVariant
+	}
+
+	return session, nil
+}
+
+func (s *SessionService) GetSessionPalette(ctx context.Context, sessionId string) (*models.SessionPaletteResponse, error) {
+	palettes, err := s.dbClient.GetSessionPalettes(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SessionPaletteResponse{
+		Palette:  summarizePalette(palettes),
+		Stickers: len(palettes),
+	}, nil
+}
+
+// GetSessionCoverage measures one surface of the session, the first one when
+// surfaceId is empty.
+func (s *SessionService) GetSessionCoverage(ctx context.Context, sessionId string, surfaceId string) (*models.SessionCoverageResponse, error) {
+	session, err := s.GetSession(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	surface := models.SessionSurface{DeviceId: DefaultDeviceID}
+	if len(session.Surfaces) > 0 && surfaceId == "" {
+		surface = session.Surfaces[0]
+	} else if surfaceId != "" {
+		found := false
+		for _, candidate := range session.Surfaces {
+			if candidate.Id == surfaceId {
+				surface, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrSurfaceNotFound
+		}
+	}
+
+	device, err := s.deviceService.GetDevice(ctx, surface.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeCoverage(surface.Stickers, device), nil
 }
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+	"time"
+)
+
+// ListEvents returns the whole edit log of a session, oldest first.
+func (s *SessionService) ListEvents(ctx context.Context, sessionId string) ([]models.SessionEvent, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	events, err := s.dbClient.ListSessionEvents(ctx, sessionId, 0, time.Time{})
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	return events, err
+}
+
+// GetSessionAt rebuilds the layout of a session as it was at the given time
+// from the newest revision at or before it, replaying the edit log after that
+// revision. Sessions without one are replayed from the start of the log.
+// Positions are in inches.
+func (s *SessionService) GetSessionAt(ctx context.Context, sessionId string, at time.Time) (*models.SessionLayoutAt, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	layout := &models.SessionLayoutAt{At: at, Surfaces: []models.SessionSurface{}}
+
+	base, err := s.dbClient.GetSessionBaseRevision(ctx, sessionId, at)
+	switch {
+	case errors.Is(err, db.ErrSessionNotFound):
+		return nil, ErrSessionNotFound
+	case errors.Is(err, db.ErrHistoryPruned):
+		return nil, ErrHistoryPruned
+	case errors.Is(err, db.ErrRevisionNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		layout.Version = base.Version
+		layout.Surfaces = base.Snapshot.Surfaces
+	}
+
+	events, err := s.dbClient.ListSessionEvents(ctx, sessionId, layout.Version, at)
+	if errors.Is(err, db.ErrSessionNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	layout.Surfaces = projectSession(layout.Surfaces, events)
+	if len(events) > 0 {
+		layout.Version = events[len(events)-1].Version
+	}
+	return layout, nil
+}
+
+// projectSession replays an edit log onto base and returns the resulting
+// surfaces, leaving base unchanged. It mirrors what the db layer does to
+// session_stickers, so replaying the whole log yields the stored layout.
+// Events for stickers or surfaces that no longer exist are skipped.
+func projectSession(base []models.SessionSurface, events []models.SessionEvent) []models.SessionSurface {
+	surfaces := copySurfaces(base)
+
+	for _, event := range events {
+		if event.Type == models.SessionEventLayoutReplaced {
+			surfaces = copySurfaces(event.Data.Surfaces)
+			continue
+		}
+
+		surface := findSurface(surfaces, event.SurfaceId)
+		if surface == nil {
+			continue
+		}
+
+		if event.Type == models.SessionEventStickerAdded {
+			if event.Data.Sticker != nil {
+				sticker := *event.Data.Sticker
+				sticker.ZIndex = len(surface.Stickers)
+				surface.Stickers = append(surface.Stickers, sticker)
+			}
+			continue
+		}
+
+		i := findSticker(surface.Stickers, event.StickerId)
+		if i < 0 {
+			continue
+		}
+
+		switch event.Type {
+		case models.SessionEventStickerMoved:
+			if event.Data.Position != nil {
+				surface.Stickers[i].Position = *event.Data.Position
+			}
+		case models.SessionEventStickerTransformed:
+			if event.Data.Transform != nil {
+				surface.Stickers[i].Transform = *event.Data.Transform
+			}
+		case models.SessionEventStickerReordered:
+			if event.Data.ZIndex != nil {
+				moveLayer(surface.Stickers, i, *event.Data.ZIndex)
+			}
+		case models.SessionEventStickerUpdated:
+			if event.Data.Sticker != nil {
+				surface.Stickers[i] = *event.Data.Sticker
+				surface.Stickers[i].ZIndex = i
+				moveLayer(surface.Stickers, i, event.Data.Sticker.ZIndex)
+			}
+		case models.SessionEventStickerRemoved:
+			surface.Stickers = append(surface.Stickers[:i], surface.Stickers[i+1:]...)
+			renumberLayers(surface.Stickers)
+		}
+	}
+
+	return surfaces
+}
+
+// copySurfaces deep-copies surfaces so later events do not modify the
+// slices of the event they came from.
+func copySurfaces(src []models.SessionSurface) []models.SessionSurface {
+	surfaces := make([]models.SessionSurface, len(src))
+	for i, surface := range src {
+		surfaces[i] = surface
+		surfaces[i].Stickers = append([]models.SavedStickerData{}, surface.Stickers...)
+		normalizeZIndices(surfaces[i].Stickers)
+	}
+	return surfaces
+}
+
+func findSurface(surfaces []models.SessionSurface, surfaceId string) *models.SessionSurface {
+	for i := range surfaces {
+		if surfaces[i].Id == surfaceId {
+			return &surfaces[i]
+		}
+	}
+	return nil
+}
+
+func findSticker(stickers []models.SavedStickerData, stickerId string) int {
+	for i := range stickers {
+		if stickers[i].StickerId == stickerId {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveLayer moves the sticker at index i, which is also its layer, to the
+// given layer clamped to the stack, and renumbers the stack.
+func moveLayer(stickers []models.SavedStickerData, i, zIndex int) {
+	zIndex = max(0, min(zIndex, len(stickers)-1))
+	sticker := stickers[i]
+	if zIndex < i {
+		copy(stickers[zIndex+1:i+1], stickers[zIndex:i])
+	} else {
+		copy(stickers[i:zIndex], stickers[i+1:zIndex+1])
+	}
+	stickers[zIndex] = sticker
+	renumberLayers(stickers)
+}
+
+func renumberLayers(stickers []models.SavedStickerData) {
+	for i := range stickers {
+		stickers[i].ZIndex = i
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestProjectSession(t *testing.T) {
+	sticker := func(id string) *models.SavedStickerData {
+		return &models.SavedStickerData{StickerId: id, URL: "https://example.com/" + id + ".png"}
+	}
+	layout := models.SessionEvent{
+		Type: models.SessionEventLayoutReplaced,
+		Data: models.SessionEventData{Surfaces: []models.SessionSurface{{
+			Id:       "lid",
+			Stickers: []models.SavedStickerData{*sticker("a"), *sticker("b")},
+		}}},
+	}
+	zIndex := func(z int) *int { return &z }
+
+	tests := []struct {
+		name      string
+		base      []models.SessionSurface
+		events    []models.SessionEvent
+		want      []string
+		positions map[string]models.Position
+		rotations map[string]float64
+	}{
+		{
+			name: "no events",
+			want: nil,
+		},
+		{
+			name:   "layout replaced",
+			events: []models.SessionEvent{layout},
+			want:   []string{"a", "b"},
+		},
+		{
+			name: "sticker added on top",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerAdded, SurfaceId: "lid", StickerId: "c", Data: models.SessionEventData{Sticker: sticker("c")}},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "sticker moved and transformed",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerMoved, SurfaceId: "lid", StickerId: "a", Data: models.SessionEventData{Position: &models.Position{X: 1.5, Y: 2}}},
+				{Type: models.SessionEventStickerTransformed, SurfaceId: "lid", StickerId: "b", Data: models.SessionEventData{Transform: &models.Transform{Rotation: 45}}},
+			},
+			want:      []string{"a", "b"},
+			positions: map[string]models.Position{"a": {X: 1.5, Y: 2}},
+			rotations: map[string]float64{"b": 45},
+		},
+		{
+			name: "sticker reordered past the top is clamped",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerReordered, SurfaceId: "lid", StickerId: "a", Data: models.SessionEventData{ZIndex: zIndex(10)}},
+			},
+			want: []string{"b", "a"},
+		},
+		{
+			name: "sticker updated keeps its requested layer",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerUpdated, SurfaceId: "lid", StickerId: "b", Data: models.SessionEventData{Sticker: &models.SavedStickerData{StickerId: "b", Position: models.Position{X: 3}}}},
+			},
+			want:      []string{"b", "a"},
+			positions: map[string]models.Position{"b": {X: 3}},
+		},
+		{
+			name: "sticker removed",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerRemoved, SurfaceId: "lid", StickerId: "a"},
+			},
+			want: []string{"b"},
+		},
+		{
+			name: "events for unknown stickers and surfaces are skipped",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerRemoved, SurfaceId: "lid", StickerId: "missing"},
+				{Type: models.SessionEventStickerAdded, SurfaceId: "missing", StickerId: "c", Data: models.SessionEventData{Sticker: sticker("c")}},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "a later layout replaces everything",
+			events: []models.SessionEvent{layout,
+				{Type: models.SessionEventStickerRemoved, SurfaceId: "lid", StickerId: "a"},
+				layout,
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "replayed onto a revision",
+			base: layout.Data.Surfaces,
+			events: []models.SessionEvent{
+				{Type: models.SessionEventStickerRemoved, SurfaceId: "lid", StickerId: "a"},
+				{Type: models.SessionEventStickerAdded, SurfaceId: "lid", StickerId: "c", Data: models.SessionEventData{Sticker: sticker("c")}},
+			},
+			want: []string{"b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			surfaces := projectSession(tt.base, tt.events)
+
+			var got []string
+			for _, surface := range surfaces {
+				for i, s := range surface.Stickers {
+					if s.ZIndex != i {
+						t.Errorf("sticker %s has zIndex %d at layer %d", s.StickerId, s.ZIndex, i)
+					}
+					got = append(got, s.StickerId)
+					if want, ok := tt.positions[s.StickerId]; ok && s.Position != want {
+						t.Errorf("sticker %s at %v, want %v", s.StickerId, s.Position, want)
+					}
+					if want, ok := tt.rotations[s.StickerId]; ok && s.Transform.Rotation != want {
+						t.Errorf("sticker %s rotated %v, want %v", s.StickerId, s.Transform.Rotation, want)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stickers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Replaying must not modify the stickers of the base or the events.
+	removeA := models.SessionEvent{Type: models.SessionEventStickerRemoved, SurfaceId: "lid", StickerId: "a"}
+	projectSession(nil, []models.SessionEvent{layout, removeA})
+	projectSession(layout.Data.Surfaces, []models.SessionEvent{removeA})
+	if id := layout.Data.Surfaces[0].Stickers[0].StickerId; id != "a" {
+		t.Errorf("replay modified the layout: first sticker is %s", id)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Characters allowed in any session ID, including ones generated before the
+// server issued them. Configured alphabets must be a subset.
+const sessionIdCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-"
+
+const (
+	defaultSessionIdLength   = 8
+	defaultSessionIdAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	minSessionIdLength       = 6
+	maxSessionIdLength       = 64
+)
+
+type SessionIDConfig struct {
+	Length   int
+	Alphabet string
+}
+
+// NewSessionIDConfig reads SESSION_ID_LENGTH and SESSION_ID_ALPHABET, falling
+// back to the 8-character alphanumeric IDs the client used to generate.
+func NewSessionIDConfig() SessionIDConfig {
+	config := SessionIDConfig{
+		Length:   defaultSessionIdLength,
+		Alphabet: defaultSessionIdAlphabet,
+	}
+
+	if value := os.Getenv("SESSION_ID_LENGTH"); value != "" {
+		length, err := strconv.Atoi(value)
+		if err != nil || length < minSessionIdLength || length > maxSessionIdLength {
+			log.Printf("Ignoring invalid SESSION_ID_LENGTH %q", value)
+		} else {
+			config.Length = length
+		}
+	}
+
+	if value := os.Getenv("SESSION_ID_ALPHABET"); value != "" {
+		if err := validateSessionIdAlphabet(value); err != nil {
+			log.Printf("Ignoring invalid SESSION_ID_ALPHABET: %v", err)
+		} else {
+			config.Alphabet = value
+		}
+	}
+
+	return config
+}
+
+func validateSessionIdAlphabet(alphabet string) error {
+	if len(alphabet) < 16 {
+		return fmt.Errorf("alphabet needs at least 16 characters")
+	}
+
+	seen := make(map[rune]bool, len(alphabet))
+	for _, r := range alphabet {
+		if !strings.ContainsRune(sessionIdCharset, r) {
+			return fmt.Errorf("character %q is not allowed", r)
+		}
+		if seen[r] {
+			return fmt.Errorf("character %q is repeated", r)
+		}
+		seen[r] = true
+	}
+	return nil
+}
+
+func generateSessionId(config SessionIDConfig) (string, error) {
+	max := big.NewInt(int64(len(config.Alphabet)))
+	id := make([]byte, config.Length)
+	for i := range id {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = config.Alphabet[n.Int64()]
+	}
+	return string(id), nil
+}
+
+// isWellFormedSessionId reports whether id could be a session ID issued under
+// the configuration, so malformed IDs are rejected before hitting the
+// database.
+func (s *SessionService) isWellFormedSessionId(id string) bool {
+	return s.idConfig.isWellFormed(id)
+}
+
+// isWellFormed accepts IDs of the configured alphabet between
+// minSessionIdLength and the configured length, so IDs issued before the
+// length was raised stay valid, and the 8-character alphanumeric IDs the
+// client used to generate.
+func (c SessionIDConfig) isWellFormed(id string) bool {
+	if len(id) == defaultSessionIdLength && onlyRunesOf(id, defaultSessionIdAlphabet) {
+		return true
+	}
+	return len(id) >= minSessionIdLength && len(id) <= c.Length && onlyRunesOf(id, c.Alphabet)
+}
+
+func onlyRunesOf(s, alphabet string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune(alphabet, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionIdIsWellFormed(t *testing.T) {
+	defaults := SessionIDConfig{Length: defaultSessionIdLength, Alphabet: defaultSessionIdAlphabet}
+	long := SessionIDConfig{Length: 16, Alphabet: "abcdefghjkmnpqrstuvwxyz23456789_"}
+
+	tests := []struct {
+		name   string
+		config SessionIDConfig
+		id     string
+		want   bool
+	}{
+		{"default id", defaults, "aB3dE5gH", true},
+		{"empty", defaults, "", false},
+		{"too long for the config", defaults, "aB3dE5gH1", false},
+		{"shorter ids up to the minimum", defaults, "aB3dE5", true},
+		{"below the minimum", defaults, "aB3dE", false},
+		{"character outside the alphabet", defaults, "aB3dE5g-", false},
+		{"path characters", defaults, "../etc/p", false},
+		{"configured id", long, "abc_23456789xyzq", true},
+		{"configured id before the length was raised", long, "abc_2345", true},
+		{"outside the configured alphabet", long, "abcdefghijklmnop", false},
+		{"legacy id under another alphabet", long, "AB1Cd0oI", true},
+		{"legacy length only for alphanumerics", long, "AB1Cd0-I", false},
+		{"over the configured length", long, strings.Repeat("a", 17), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.isWellFormed(tt.id); got != tt.want {
+				t.Errorf("isWellFormed(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSessionIdIsWellFormed(t *testing.T) {
+	configs := []SessionIDConfig{
+		{Length: defaultSessionIdLength, Alphabet: defaultSessionIdAlphabet},
+		{Length: maxSessionIdLength, Alphabet: sessionIdCharset},
+		{Length: minSessionIdLength, Alphabet: "0123456789abcdef"},
+	}
+
+	for _, config := range configs {
+		for i := 0; i < 20; i++ {
+			id, err := generateSessionId(config)
+			if err != nil {
+				t.Fatalf("generateSessionId() error = %v", err)
+			}
+			if !config.isWellFormed(id) {
+				t.Errorf("generated id %q is not well formed under %+v", id, config)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+)
+
+var (
+	ErrInvalidPatch     = errors.New("invalid patch operation")
+	ErrStickerNotFound  = errors.New("sticker not found")
+	ErrStickerExists    = errors.New("sticker already exists")
+	ErrInvalidStickerId = errors.New("sticker id is required and must match the URL")
+)
+
+const maxPatchOperations = 500
+
+// translatePlacementError maps db errors for single placements to service
+// errors.
+func translatePlacementError(err error) error {
+	switch {
+	case errors.Is(err, db.ErrSessionNotFound):
+		return ErrSessionNotFound
+	case errors.Is(err, db.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, db.ErrSurfaceNotFound):
+		return ErrSurfaceNotFound
+	case errors.Is(err, db.ErrStickerNotFound):
+		return ErrStickerNotFound
+	case errors.Is(err, db.ErrStickerExists):
+		return ErrStickerExists
+	case errors.Is(err, db.ErrSurfaceFull):
+		return ErrTooManyStickers
+	}
+	return err
+}
+
+// PatchSession applies the operations to the stored placements in one
+// transaction, recording the result as a revision by author, and returns the
+// new version. Positions are in inches.
+func (s *SessionService) PatchSession(ctx context.Context, sessionId string, ops []models.SessionPatchOperation, author string, expectedVersion int) (int, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return 0, ErrInvalidSessionId
+	}
+	if len(ops) == 0 || len(ops) > maxPatchOperations {
+		return 0, ErrInvalidPatch
+	}
+
+	for i := range ops {
+		if err := normalizePatchOperation(&ops[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	version, err := s.dbClient.PatchSession(ctx, sessionId, ops, author, expectedVersion)
+	return s.finishStickerWrite(ctx, sessionId, version, err)
+}
+
+// finishStickerWrite translates the error of a placement write or, if it
+// succeeded, applies the revision retention policy to the revision it
+// recorded.
+func (s *SessionService) finishStickerWrite(ctx context.Context, sessionId string, version int, err error) (int, error) {
+	if err != nil {
+		return 0, translatePlacementError(err)
+	}
+	s.pruneRevisions(ctx, sessionId)
+	return version, nil
+}
+
+// normalizePatchOperation checks that the operation carries the fields its
+// kind needs and normalizes stickers and transforms like a full save does.
+func normalizePatchOperation(op *models.SessionPatchOperation) error {
+	if len(op.SurfaceId) > maxSurfaceIdLength {
+		return ErrInvalidSurface
+	}
+
+	if op.Op == models.PatchOpAdd {
+		if op.Sticker == nil {
+			return ErrInvalidPatch
+		}
+		if op.StickerId == "" {
+			op.StickerId = op.Sticker.StickerId
+		}
+		if op.StickerId == "" || op.StickerId != op.Sticker.StickerId {
+			return ErrInvalidPatch
+		}
+		return normalizeSticker(op.Sticker)
+	}
+
+	if op.StickerId == "" {
+		return ErrInvalidPatch
+	}
+
+	switch op.Op {
+	case models.PatchOpMove:
+		if op.Position == nil {
+			return ErrInvalidPatch
+		}
+	case models.PatchOpTransform:
+		if op.Transform == nil {
+			return ErrInvalidPatch
+		}
+		return normalizeTransform(op.Transform)
+	case models.PatchOpReorder:
+		if op.ZIndex == nil {
+			return ErrInvalidPatch
+		}
+	case models.PatchOpRemove:
+	default:
+		return ErrInvalidPatch
+	}
+
+	return nil
+}
+
+// ListStickers returns the placements of one surface, or of every surface
+// when surfaceId is empty.
+func (s *SessionService) ListStickers(ctx context.Context, sessionId, surfaceId string) ([]models.PlacedSticker, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	stickers, err := s.dbClient.ListSessionStickers(ctx, sessionId, surfaceId)
+	return stickers, translatePlacementError(err)
+}
+
+// GetSticker returns a single placement. An empty surfaceId means the first
+// surface.
+func (s *SessionService) GetSticker(ctx context.Context, sessionId, surfaceId, stickerId string) (*models.PlacedSticker, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return nil, ErrInvalidSessionId
+	}
+
+	sticker, err := s.dbClient.GetSessionSticker(ctx, sessionId, surfaceId, stickerId)
+	return sticker, translatePlacementError(err)
+}
+
+// AddSticker places a new sticker on top of its surface and returns the new
+// session version.
+func (s *SessionService) AddSticker(ctx context.Context, sessionId string, sticker *models.PlacedSticker, author string, expectedVersion int) (int, error) {
+	if err := s.validatePlacedSticker(sessionId, sticker); err != nil {
+		return 0, err
+	}
+
+	version, err := s.dbClient.AddSessionSticker(ctx, sessionId, sticker, author, expectedVersion)
+	return s.finishStickerWrite(ctx, sessionId, version, err)
+}
+
+// PlaceLibraryItem adds a copy of a library item the user can see to the
+// session, without resolving the sticker again, and returns the placement
+// with the new session version. The caller must already have edit access to
+// the session.
+func (s *SessionService) PlaceLibraryItem(ctx context.Context, sessionId, userId, author string, req *models.PlaceLibraryItemRequest, expectedVersion int) (*models.PlacedSticker, int, error) {
+	item, err := getLibraryItem(ctx, s.dbClient, req.ItemId, userId, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sticker := &models.PlacedSticker{
+		SurfaceId: req.SurfaceId,
+		SavedStickerData: models.SavedStickerData{
+			StickerId: req.StickerId,
+			Title:     item.Title,
+			URL:       item.URL,
+			Size:      item.Size,
+			Position:  req.Position,
+			Source:    item.Source,
+			Transform: req.Transform,
+		},
+	}
+	if sticker.StickerId == "" {
+		if sticker.StickerId, err = generateId(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	version, err := s.AddSticker(ctx, sessionId, sticker, author, expectedVersion)
+	if err != nil {
+		return nil, 0, err
+	}
+	return sticker, version, nil
+}
+
+// UpdateSticker replaces an existing placement and returns the new session
+// version.
+func (s *SessionService) UpdateSticker(ctx context.Context, sessionId string, sticker *models.PlacedSticker, author string, expectedVersion int) (int, error) {
+	if err := s.validatePlacedSticker(sessionId, sticker); err != nil {
+		return 0, err
+	}
+
+	version, err := s.dbClient.UpdateSessionSticker(ctx, sessionId, sticker, author, expectedVersion)
+	return s.finishStickerWrite(ctx, sessionId, version, err)
+}
+
+// DeleteSticker removes a placement and returns the new session version.
+func (s *SessionService) DeleteSticker(ctx context.Context, sessionId, surfaceId, stickerId, author string, expectedVersion int) (int, error) {
+	if !s.isWellFormedSessionId(sessionId) {
+		return 0, ErrInvalidSessionId
+	}
+
+	version, err := s.dbClient.DeleteSessionSticker(ctx, sessionId, surfaceId, stickerId, author, expectedVersion)
+	return s.finishStickerWrite(ctx, sessionId, version, err)
+}
+
+func (s *SessionService) validatePlacedSticker(sessionId string, sticker *models.PlacedSticker) error {
+	if !s.isWellFormedSessionId(sessionId) {
+		return ErrInvalidSessionId
+	}
+	if len(sticker.SurfaceId) > maxSurfaceIdLength {
+		return ErrInvalidSurface
+	}
+	if sticker.StickerId == "" {
+		return ErrInvalidStickerId
+	}
+	return normalizeSticker(&sticker.SavedStickerData)
+}
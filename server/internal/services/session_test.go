@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"server/internal/models"
+)
+
+func TestNormalizeTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform models.Transform
+		want      models.Transform
+		wantErr   bool
+	}{
+		{"defaults", models.Transform{}, models.Transform{Scale: 1, Opacity: 1}, false},
+		{"kept as is", models.Transform{Rotation: 90, Scale: 2, Opacity: 0.5, FlipX: true}, models.Transform{Rotation: 90, Scale: 2, Opacity: 0.5, FlipX: true}, false},
+		{"rotation wrapped", models.Transform{Rotation: 450}, models.Transform{Rotation: 90, Scale: 1, Opacity: 1}, false},
+		{"negative rotation wrapped", models.Transform{Rotation: -90}, models.Transform{Rotation: 270, Scale: 1, Opacity: 1}, false},
+		{"full turn", models.Transform{Rotation: 360}, models.Transform{Rotation: 0, Scale: 1, Opacity: 1}, false},
+		{"smallest scale", models.Transform{Scale: minStickerScale}, models.Transform{Scale: minStickerScale, Opacity: 1}, false},
+		{"largest scale", models.Transform{Scale: maxStickerScale}, models.Transform{Scale: maxStickerScale, Opacity: 1}, false},
+		{"scale too small", models.Transform{Scale: minStickerScale / 2}, models.Transform{}, true},
+		{"scale too large", models.Transform{Scale: maxStickerScale + 1}, models.Transform{}, true},
+		{"negative scale", models.Transform{Scale: -1}, models.Transform{}, true},
+		{"NaN scale", models.Transform{Scale: math.NaN()}, models.Transform{}, true},
+		{"NaN rotation", models.Transform{Rotation: math.NaN()}, models.Transform{}, true},
+		{"infinite rotation", models.Transform{Rotation: math.Inf(1)}, models.Transform{}, true},
+		{"negative opacity", models.Transform{Opacity: -0.5}, models.Transform{}, true},
+		{"opacity over 1", models.Transform{Opacity: 1.5}, models.Transform{}, true},
+		{"NaN opacity", models.Transform{Opacity: math.NaN()}, models.Transform{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform := tt.transform
+			err := normalizeTransform(&transform)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransform) {
+					t.Errorf("normalizeTransform() error = %v, want ErrInvalidTransform", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeTransform() error = %v", err)
+			}
+			if transform != tt.want {
+				t.Errorf("normalizeTransform() = %+v, want %+v", transform, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeZIndices(t *testing.T) {
+	tests := []struct {
+		name    string
+		zIndex  map[string]int
+		order   []string
+		wantIds []string
+	}{
+		{"empty", nil, nil, nil},
+		{"already numbered", map[string]int{"a": 0, "b": 1, "c": 2}, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"sorted by layer", map[string]int{"a": 2, "b": 0, "c": 1}, []string{"a", "b", "c"}, []string{"b", "c", "a"}},
+		{"gaps closed", map[string]int{"a": 10, "b": -3, "c": 40}, []string{"a", "b", "c"}, []string{"b", "a", "c"}},
+		{"ties keep request order", map[string]int{"a": 1, "b": 0, "c": 1}, []string{"a", "b", "c"}, []string{"b", "a", "c"}},
+		{"no layers sent", map[string]int{}, []string{"c", "a", "b"}, []string{"c", "a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stickers []models.SavedStickerData
+			for _, id := range tt.order {
+				stickers = append(stickers, models.SavedStickerData{StickerId: id, ZIndex: tt.zIndex[id]})
+			}
+
+			normalizeZIndices(stickers)
+
+			var ids []string
+			for i, sticker := range stickers {
+				if sticker.ZIndex != i {
+					t.Errorf("sticker %s has zIndex %d at layer %d", sticker.StickerId, sticker.ZIndex, i)
+				}
+				ids = append(ids, sticker.StickerId)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIds) {
+				t.Errorf("order = %v, want %v", ids, tt.wantIds)
+			}
+		})
+	}
+}
@@ -1,25 +1,36 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"image"
 	"io"
+	"log"
+	"math"
 	"net/http"
 	"server/internal/models"
 	"server/internal/utils"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 )
 
-type StickerService struct{}
+type StickerService struct {
+	imageService   *ImageService
+	productService *ProductService
+}
 
-func NewStickerService() *StickerService {
-	return &StickerService{}
+func NewStickerService(imageService *ImageService, productService *ProductService) *StickerService {
+	return &StickerService{
+		imageService:   imageService,
+		productService: productService,
+	}
 }
 
-func (s *StickerService) FetchProductInfo(url string) (models.StickerDataResponse, error) {
+func (s *StickerService) FetchProductInfo(ctx context.Context, url string) (models.StickerDataResponse, error) {
 	resp, err := http.Get(url)
 	if err != nil {
 		return models.StickerDataResponse{}, err
@@ -35,7 +46,116 @@ func (s *StickerService) FetchProductInfo(url string) (models.StickerDataRespons
 		return models.StickerDataResponse{}, err
 	}
 
-	return s.extractProductInfo(string(body))
+	stickerData, err := s.extractProductInfo(string(body))
+	if err != nil {
+		return models.StickerDataResponse{}, err
+	}
+
+	phash := s.processImage(ctx, &stickerData)
+
+	productID, err := s.productService.RecordProduct(ctx, &models.Product{
+		SourceURL:      url,
+		ProductImage:   stickerData.ProductImage,
+		ImageHash:      stickerData.ImageHash,
+		Size:           stickerData.Size,
+		Palette:        stickerData.Palette,
+		PerceptualHash: phash,
+	})
+	if err != nil {
+		log.Printf("Failed to record product %s: %v", url, err)
+	} else {
+		stickerData.ProductID = productID
+	}
+
+	return stickerData, nil
+}
+
+// processImage downloads the product image once to check it against the
+// declared size, mirror it and compute its perceptual hash and palette.
+// Failures are logged and leave the response pointing at the original image.
+func (s *StickerService) processImage(ctx context.Context, stickerData *models.StickerDataResponse) *int64 {
+	data, err := fetchImage(stickerData.ProductImage)
+	if err != nil {
+		log.Printf("Failed to fetch image %s: %v", stickerData.ProductImage, err)
+		return nil
+	}
+
+	img, err := decodeSourceImage(data)
+	if err != nil {
+		log.Printf("Failed to decode image %s: %v", stickerData.ProductImage, err)
+		return nil
+	}
+
+	checkAspectRatio(stickerData, img.decoded.Bounds())
+
+	phash := int64(utils.DifferenceHash(img.decoded))
+	stickerData.Palette = img.palette
+
+	hash, err := s.imageService.MirrorImage(ctx, img, stickerData.ProductImage)
+	if err != nil {
+		log.Printf("Failed to mirror image %s: %v", stickerData.ProductImage, err)
+		return &phash
+	}
+
+	stickerData.ImageHash = hash
+	stickerData.Images = imageURLs(hash)
+
+	return &phash
+}
+
+// ProcessExternalImage builds a sticker from an arbitrary image URL and
+// user-provided dimensions. The image is always mirrored, so the sticker is
+// served through /images rather than from the third-party host.
+func (s *StickerService) ProcessExternalImage(ctx context.Context, imageURL string, size models.Size) (models.StickerDataResponse, error) {
+	data, err := fetchImage(imageURL)
+	if err != nil {
+		if errors.Is(err, ErrNotAnImage) || errors.Is(err, ErrBlockedImageAddress) {
+			return models.StickerDataResponse{}, err
+		}
+		return models.StickerDataResponse{}, fmt.Errorf("failed to fetch image: %w", err)
+	}
+
+	return s.mirroredSticker(ctx, data, imageURL, size)
+}
+
+// ProcessUploadedImage builds a sticker from image data uploaded by the
+// client, mirrored with its derivatives like an external image.
+func (s *StickerService) ProcessUploadedImage(ctx context.Context, r io.Reader, size models.Size) (models.StickerDataResponse, error) {
+	data, err := readImageData(r)
+	if err != nil {
+		return models.StickerDataResponse{}, err
+	}
+
+	return s.mirroredSticker(ctx, data, "", size)
+}
+
+// mirroredSticker stores image data under /images and describes it as an
+// external sticker of the given size. sourceURL is empty for uploads.
+func (s *StickerService) mirroredSticker(ctx context.Context, data []byte, sourceURL string, size models.Size) (models.StickerDataResponse, error) {
+	img, err := decodeSourceImage(data)
+	if errors.Is(err, ErrImageTooLarge) {
+		return models.StickerDataResponse{}, err
+	}
+	if err != nil {
+		return models.StickerDataResponse{}, ErrNotAnImage
+	}
+
+	hash, err := s.imageService.MirrorImage(ctx, img, sourceURL)
+	if err != nil {
+		return models.StickerDataResponse{}, err
+	}
+
+	stickerData := models.StickerDataResponse{
+		ProductImage: fmt.Sprintf("/images/%s", hash),
+		Size:         size,
+		Source:       models.StickerSourceExternal,
+		ImageHash:    hash,
+		Images:       imageURLs(hash),
+		Palette:      img.palette,
+	}
+	checkAspectRatio(&stickerData, img.decoded.Bounds())
+
+	return stickerData, nil
 }
 
 func getImgUrl(productPreview *html.Node) (string, error) {
@@ -117,6 +237,34 @@ func getSize(productPreview *html.Node) (float64, float64, error) {
 	return w, h, nil
 }
 
+// getTitle returns the product name from the page's og:title, falling back
+// to its <title>. Pages without either give "", since the title is only used
+// to label the sticker. It is cut to the longest title a session accepts.
+func getTitle(htmlNode *html.Node) string {
+	var title string
+	if meta, err := utils.Traverse(htmlNode, []utils.Path{
+		{Tag: "head", Attr: "", Val: ""},
+		{Tag: "meta", Attr: "property", Val: "og:title"},
+	}); err == nil {
+		title, _ = utils.GetAttr(meta, "content")
+	}
+	if title == "" {
+		if node, err := utils.Traverse(htmlNode, []utils.Path{
+			{Tag: "head", Attr: "", Val: ""},
+			{Tag: "title", Attr: "", Val: ""},
+		}); err == nil {
+			title, _ = utils.GetTextContent(node)
+		}
+	}
+
+	title = strings.TrimSpace(title)
+	for len(title) > maxStickerTitleLength {
+		_, size := utf8.DecodeLastRuneInString(title)
+		title = title[:len(title)-size]
+	}
+	return title
+}
+
 func (s *StickerService) extractProductInfo(htmlContent string) (models.StickerDataResponse, error) {
 	doc, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
@@ -163,7 +311,47 @@ func (s *StickerService) extractProductInfo(htmlContent string) (models.StickerD
 	size := models.Size{Width: w, Height: h}
 
 	return models.StickerDataResponse{
+		Title:        getTitle(htmlNode),
 		ProductImage: imgUrl,
 		Size:         size,
+		Source:       models.StickerSourceStickerMule,
 	}, nil
 }
+
+// Relative difference allowed between the image aspect ratio and the declared
+// size before the size is considered to belong to a different variant.
+const aspectRatioTolerance = 0.05
+
+func checkAspectRatio(stickerData *models.StickerDataResponse, bounds image.Rectangle) {
+	pw, ph := bounds.Dx(), bounds.Dy()
+	if pw == 0 || ph == 0 {
+		log.Printf("Skipping aspect ratio check for %s: image is empty", stickerData.ProductImage)
+		return
+	}
+	stickerData.ImageSize = &models.Size{Width: float64(pw), Height: float64(ph)}
+
+	declared := stickerData.Size
+	if declared.Width <= 0 || declared.Height <= 0 {
+		return
+	}
+
+	imageRatio := float64(pw) / float64(ph)
+	declaredRatio := declared.Width / declared.Height
+	if math.Abs(imageRatio-declaredRatio)/declaredRatio <= aspectRatioTolerance {
+		return
+	}
+
+	// Keep the longer declared side and derive the other one from the image.
+	corrected := declared
+	if declared.Width >= declared.Height {
+		corrected.Height = math.Round(declared.Width/imageRatio*100) / 100
+	} else {
+		corrected.Width = math.Round(declared.Height*imageRatio*100) / 100
+	}
+
+	stickerData.CorrectedSize = &corrected
+	stickerData.Warning = fmt.Sprintf(
+		"declared size %.2fx%.2fin does not match image aspect ratio %dx%d",
+		declared.Width, declared.Height, pw, ph,
+	)
+}
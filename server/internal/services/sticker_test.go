@@ -0,0 +1,118 @@
+package services
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"server/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+func TestCheckAspectRatio(t *testing.T) {
+	tests := []struct {
+		name          string
+		declared      models.Size
+		bounds        image.Rectangle
+		wantImageSize *models.Size
+		wantCorrected *models.Size
+	}{
+		{
+			name:          "matching ratio",
+			declared:      models.Size{Width: 3, Height: 2},
+			bounds:        image.Rect(0, 0, 300, 200),
+			wantImageSize: &models.Size{Width: 300, Height: 200},
+		},
+		{
+			name:          "within tolerance",
+			declared:      models.Size{Width: 3, Height: 2},
+			bounds:        image.Rect(0, 0, 306, 200),
+			wantImageSize: &models.Size{Width: 306, Height: 200},
+		},
+		{
+			name:          "wide declared size keeps its width",
+			declared:      models.Size{Width: 4, Height: 2},
+			bounds:        image.Rect(0, 0, 300, 300),
+			wantImageSize: &models.Size{Width: 300, Height: 300},
+			wantCorrected: &models.Size{Width: 4, Height: 4},
+		},
+		{
+			name:          "tall declared size keeps its height",
+			declared:      models.Size{Width: 2, Height: 3},
+			bounds:        image.Rect(0, 0, 300, 300),
+			wantImageSize: &models.Size{Width: 300, Height: 300},
+			wantCorrected: &models.Size{Width: 3, Height: 3},
+		},
+		{
+			name:          "corrected side rounded to hundredths",
+			declared:      models.Size{Width: 3, Height: 3},
+			bounds:        image.Rect(0, 0, 300, 700),
+			wantImageSize: &models.Size{Width: 300, Height: 700},
+			wantCorrected: &models.Size{Width: 3, Height: 7},
+		},
+		{
+			name:     "empty image",
+			declared: models.Size{Width: 3, Height: 2},
+			bounds:   image.Rect(0, 0, 0, 200),
+		},
+		{
+			name:          "no declared size",
+			bounds:        image.Rect(0, 0, 300, 200),
+			wantImageSize: &models.Size{Width: 300, Height: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := models.StickerDataResponse{ProductImage: "https://example.com/a.png", Size: tt.declared}
+			checkAspectRatio(&data, tt.bounds)
+
+			if !equalSize(data.ImageSize, tt.wantImageSize) {
+				t.Errorf("ImageSize = %v, want %v", data.ImageSize, tt.wantImageSize)
+			}
+			if !equalSize(data.CorrectedSize, tt.wantCorrected) {
+				t.Errorf("CorrectedSize = %v, want %v", data.CorrectedSize, tt.wantCorrected)
+			}
+			if (data.Warning != "") != (tt.wantCorrected != nil) {
+				t.Errorf("Warning = %q, want one only with a corrected size", data.Warning)
+			}
+			if data.Size != tt.declared {
+				t.Errorf("declared size changed to %v", data.Size)
+			}
+		})
+	}
+}
+
+func equalSize(a, b *models.Size) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestGetTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"og:title", `<meta property="og:title" content="Rainbow Cat"><title>Rainbow Cat | Shop</title>`, "Rainbow Cat"},
+		{"falls back to title", `<title> Rainbow Cat </title>`, "Rainbow Cat"},
+		{"empty og:title falls back", `<meta property="og:title" content=""><title>Rainbow Cat</title>`, "Rainbow Cat"},
+		{"no title", `<meta charset="utf-8">`, ""},
+		{"cut to the longest title", `<title>` + strings.Repeat("é", 200) + `</title>`, strings.Repeat("é", maxStickerTitleLength/2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<!DOCTYPE html><html><head>" + tt.head + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := getTitle(doc.FirstChild.NextSibling); got != tt.want {
+				t.Errorf("getTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/mail"
+	"os"
+	"server/internal/db"
+	"server/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidEmail       = errors.New("email address is invalid")
+	ErrInvalidPassword    = errors.New("password must be 8-72 characters")
+	ErrInvalidDisplayName = errors.New("display name is too long")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrInvalidLogin       = errors.New("wrong email or password")
+	ErrNotLoggedIn        = errors.New("not logged in")
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything past 72 bytes.
+	maxPasswordLength    = 72
+	passwordCost         = 12
+	maxEmailLength       = 254
+	maxDisplayNameLength = 255
+	idBytes              = 16
+
+	defaultLoginTTL = 30 * 24 * time.Hour
+
+	// Failed logins allowed per window, for an email and for an IP across
+	// all emails.
+	loginAttemptWindow     = 15 * time.Minute
+	maxLoginFailuresPerKey = 10
+	maxLoginFailuresPerIP  = 50
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so a
+// failed login takes as long whether or not the account exists.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not a real password"), passwordCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+type LoginConfig struct {
+	TTL time.Duration
+}
+
+// NewLoginConfig reads LOGIN_SESSION_TTL_DAYS, keeping users logged in for 30
+// days by default.
+func NewLoginConfig() LoginConfig {
+	config := LoginConfig{TTL: defaultLoginTTL}
+
+	if value := os.Getenv("LOGIN_SESSION_TTL_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			log.Printf("Ignoring invalid LOGIN_SESSION_TTL_DAYS %q", value)
+		} else {
+			config.TTL = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	return config
+}
+
+// LoginSession is a login handed to the client. Only the hash of Token is
+// stored.
+type LoginSession struct {
+	User      *models.User
+	Token     string
+	ExpiresAt time.Time
+}
+
+type UserService struct {
+	dbClient      *db.Client
+	config        LoginConfig
+	emailAttempts *attemptLimiter
+	ipAttempts    *attemptLimiter
+}
+
+func NewUserService(dbClient *db.Client, config LoginConfig) *UserService {
+	return &UserService{
+		dbClient:      dbClient,
+		config:        config,
+		emailAttempts: newAttemptLimiter(maxLoginFailuresPerKey, loginAttemptWindow),
+		ipAttempts:    newAttemptLimiter(maxLoginFailuresPerIP, loginAttemptWindow),
+	}
+}
+
+// normalizeEmail lowercases the address so it matches however it is typed.
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) > maxEmailLength {
+		return "", ErrInvalidEmail
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// generateId returns a random id for users and workspaces.
+func generateId() (string, error) {
+	raw := make([]byte, idBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Signup registers a user and logs them in.
+func (s *UserService) Signup(ctx context.Context, req *models.SignupRequest) (*LoginSession, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+	displayName := strings.TrimSpace(req.DisplayName)
+	if len(displayName) > maxDisplayNameLength {
+		return nil, ErrInvalidDisplayName
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), passwordCost)
+	if err != nil {
+		return nil, err
+	}
+	id, err := generateId()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Id: id, Email: email, DisplayName: displayName}
+	err = s.dbClient.CreateUser(ctx, user, hash)
+	if errors.Is(err, db.ErrEmailTaken) {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startLogin(ctx, user)
+}
+
+// Login checks the password and starts a login session. Attempts are
+// counted per email and per client IP before the password is checked and
+// refunded if it is right; once either is over its limit a RetryError is
+// returned without checking the password.
+func (s *UserService) Login(ctx context.Context, req *models.LoginRequest, ip string) (*LoginSession, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, ErrInvalidLogin
+	}
+
+	now := time.Now()
+	emailKey, ipKey := "email:"+email, "ip:"+ip
+	if wait := s.emailAttempts.reserve(emailKey, now); wait > 0 {
+		return nil, &RetryError{RetryAfter: wait}
+	}
+	if wait := s.ipAttempts.reserve(ipKey, now); wait > 0 {
+		s.emailAttempts.refund(emailKey)
+		return nil, &RetryError{RetryAfter: wait}
+	}
+	refund := func() {
+		s.emailAttempts.refund(emailKey)
+		s.ipAttempts.refund(ipKey)
+	}
+
+	user, hash, err := s.dbClient.GetUserByEmail(ctx, email)
+	if errors.Is(err, db.ErrUserNotFound) {
+		hash = dummyPasswordHash()
+	} else if err != nil {
+		refund()
+		return nil, err
+	}
+
+	// The attempt is already counted; only a correct password gives it back.
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || user == nil {
+		log.Printf("Failed login for %s from %s", email, ip)
+		return nil, ErrInvalidLogin
+	}
+
+	refund()
+	return s.startLogin(ctx, user)
+}
+
+func (s *UserService) startLogin(ctx context.Context, user *models.User) (*LoginSession, error) {
+	token, hash, err := generateSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.config.TTL)
+	if err := s.dbClient.CreateLoginSession(ctx, hash, user.Id, expiresAt); err != nil {
+		return nil, err
+	}
+	return &LoginSession{User: user, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// Authenticate returns the user logged in with the token, or ErrNotLoggedIn.
+func (s *UserService) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrNotLoggedIn
+	}
+
+	user, err := s.dbClient.GetLoginSessionUser(ctx, hashSessionToken(token))
+	if errors.Is(err, db.ErrLoginSessionNotFound) {
+		return nil, ErrNotLoggedIn
+	}
+	return user, err
+}
+
+// Logout ends the login session of the token.
+func (s *UserService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.dbClient.DeleteLoginSession(ctx, hashSessionToken(token))
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"server/internal/db"
+	"server/internal/models"
+	"strings"
+)
+
+var (
+	ErrWorkspaceNotFound     = errors.New("workspace not found")
+	ErrWorkspaceRoleRequired = errors.New("your workspace role does not allow this")
+	ErrInvalidWorkspaceName  = errors.New("workspace name must be 1-255 characters")
+	ErrInvalidRole           = errors.New("role must be owner, editor or viewer")
+	ErrUserNotFound          = errors.New("no user is registered with that email")
+	ErrMemberNotFound        = errors.New("workspace member not found")
+	ErrMemberExists          = errors.New("user is already a member")
+	ErrLastOwner             = errors.New("a workspace needs at least one owner")
+)
+
+const (
+	maxWorkspaceNameLength = 255
+	maxWorkspaceIdLength   = 64
+)
+
+// workspaceRoleRanks orders roles so checks can ask for a minimum role.
+var workspaceRoleRanks = map[string]int{
+	models.WorkspaceRoleViewer: 1,
+	models.WorkspaceRoleEditor: 2,
+	models.WorkspaceRoleOwner:  3,
+}
+
+// roleAtLeast reports whether role grants at least what min does. The empty
+// role of non-members ranks below every role.
+func roleAtLeast(role, min string) bool {
+	return workspaceRoleRanks[role] >= workspaceRoleRanks[min]
+}
+
+func isValidRole(role string) bool {
+	_, ok := workspaceRoleRanks[role]
+	return ok
+}
+
+// requireWorkspaceRole returns ErrWorkspaceNotFound if the user is not a
+// member of the workspace and ErrWorkspaceRoleRequired if their role is below
+// min.
+func requireWorkspaceRole(ctx context.Context, dbClient *db.Client, workspaceId, userId, min string) error {
+	if userId == "" {
+		return ErrNotLoggedIn
+	}
+	if workspaceId == "" || len(workspaceId) > maxWorkspaceIdLength {
+		return ErrWorkspaceNotFound
+	}
+
+	role, err := dbClient.GetWorkspaceRole(ctx, workspaceId, userId)
+	switch {
+	case err != nil:
+		return err
+	case role == "":
+		return ErrWorkspaceNotFound
+	case !roleAtLeast(role, min):
+		return ErrWorkspaceRoleRequired
+	}
+	return nil
+}
+
+// translateWorkspaceError maps db errors for workspaces to service errors.
+func translateWorkspaceError(err error) error {
+	switch {
+	case errors.Is(err, db.ErrWorkspaceNotFound):
+		return ErrWorkspaceNotFound
+	case errors.Is(err, db.ErrMemberNotFound):
+		return ErrMemberNotFound
+	case errors.Is(err, db.ErrMemberExists):
+		return ErrMemberExists
+	case errors.Is(err, db.ErrLastOwner):
+		return ErrLastOwner
+	case errors.Is(err, db.ErrSessionNotFound):
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+type WorkspaceService struct {
+	dbClient *db.Client
+}
+
+func NewWorkspaceService(dbClient *db.Client) *WorkspaceService {
+	return &WorkspaceService{
+		dbClient: dbClient,
+	}
+}
+
+// CreateWorkspace creates a workspace owned by the user.
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, userId string, req *models.CreateWorkspaceRequest) (*models.Workspace, error) {
+	if userId == "" {
+		return nil, ErrNotLoggedIn
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxWorkspaceNameLength {
+		return nil, ErrInvalidWorkspaceName
+	}
+
+	id, err := generateId()
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &models.Workspace{Id: id, Name: name}
+	if err := s.dbClient.CreateWorkspace(ctx, workspace, userId); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// ListWorkspaces returns the workspaces the user belongs to.
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context, userId string) ([]models.Workspace, error) {
+	if userId == "" {
+		return nil, ErrNotLoggedIn
+	}
+	return s.dbClient.ListUserWorkspaces(ctx, userId)
+}
+
+// GetWorkspace returns a workspace the user is a member of.
+func (s *WorkspaceService) GetWorkspace(ctx context.Context, userId, workspaceId string) (*models.Workspace, error) {
+	if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.dbClient.GetWorkspace(ctx, workspaceId, userId)
+	return workspace, translateWorkspaceError(err)
+}
+
+// DeleteWorkspace deletes a workspace. Only owners may do this.
+func (s *WorkspaceService) DeleteWorkspace(ctx context.Context, userId, workspaceId string) error {
+	if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+	return translateWorkspaceError(s.dbClient.DeleteWorkspace(ctx, workspaceId))
+}
+
+// ListMembers returns the members of a workspace the user belongs to.
+func (s *WorkspaceService) ListMembers(ctx context.Context, userId, workspaceId string) ([]models.WorkspaceMember, error) {
+	if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+
+	members, err := s.dbClient.ListWorkspaceMembers(ctx, workspaceId)
+	return members, translateWorkspaceError(err)
+}
+
+// AddMember adds the user registered with req.Email. Only owners may do this.
+func (s *WorkspaceService) AddMember(ctx context.Context, userId, workspaceId string, req *models.AddMemberRequest) (*models.WorkspaceMember, error) {
+	if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, models.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+	if !isValidRole(req.Role) {
+		return nil, ErrInvalidRole
+	}
+
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	user, _, err := s.dbClient.GetUserByEmail(ctx, email)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.dbClient.AddWorkspaceMember(ctx, workspaceId, user.Id, req.Role); err != nil {
+		return nil, translateWorkspaceError(err)
+	}
+	return &models.WorkspaceMember{
+		UserId:      user.Id,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Role:        req.Role,
+	}, nil
+}
+
+// UpdateMember changes the role of a member. Only owners may do this, and
+// the last owner cannot be demoted.
+func (s *WorkspaceService) UpdateMember(ctx context.Context, userId, workspaceId, memberId, role string) error {
+	if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+	if !isValidRole(role) {
+		return ErrInvalidRole
+	}
+	return translateWorkspaceError(s.dbClient.SetWorkspaceMemberRole(ctx, workspaceId, memberId, role))
+}
+
+// RemoveMember removes a member. Owners may remove anyone and every member
+// may leave, except the last owner.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userId, workspaceId, memberId string) error {
+	min := models.WorkspaceRoleOwner
+	if memberId == userId {
+		min = models.WorkspaceRoleViewer
+	}
+	if err := requireWorkspaceRole(ctx, s.dbClient, workspaceId, userId, min); err != nil {
+		return err
+	}
+	return translateWorkspaceError(s.dbClient.RemoveWorkspaceMember(ctx, workspaceId, memberId))
+}
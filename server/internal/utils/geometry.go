@@ -0,0 +1,113 @@
+package utils
+
+import "math"
+
+type Point struct {
+	X, Y float64
+}
+
+// Polygon is a list of vertices in order. All polygons produced here are
+// convex.
+type Polygon []Point
+
+// RotatedRect returns the corners of a w×h rectangle centered at c and
+// rotated clockwise by the given angle in degrees, matching CSS rotate() in a
+// y-down coordinate system.
+func RotatedRect(c Point, w, h, degrees float64) Polygon {
+	rad := degrees * math.Pi / 180
+	cos, sin := math.Cos(rad), math.Sin(rad)
+	hw, hh := w/2, h/2
+
+	corners := []Point{{-hw, -hh}, {hw, -hh}, {hw, hh}, {-hw, hh}}
+	polygon := make(Polygon, len(corners))
+	for i, p := range corners {
+		polygon[i] = Point{
+			X: c.X + p.X*cos - p.Y*sin,
+			Y: c.Y + p.X*sin + p.Y*cos,
+		}
+	}
+	return polygon
+}
+
+// Area returns the absolute area of the polygon.
+func (p Polygon) Area() float64 {
+	return math.Abs(signedArea(p))
+}
+
+// Bounds returns the axis-aligned bounding box of the polygon.
+func (p Polygon) Bounds() (Point, Point) {
+	min := Point{math.Inf(1), math.Inf(1)}
+	max := Point{math.Inf(-1), math.Inf(-1)}
+	for _, v := range p {
+		min.X, min.Y = math.Min(min.X, v.X), math.Min(min.Y, v.Y)
+		max.X, max.Y = math.Max(max.X, v.X), math.Max(max.Y, v.Y)
+	}
+	return min, max
+}
+
+// Contains reports whether pt lies inside the polygon, using ray casting so it
+// also works for non-convex polygons.
+func (p Polygon) Contains(pt Point) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) &&
+			pt.X < (b.X-a.X)*(pt.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// IntersectConvex clips subject against the convex polygon clip
+// (Sutherland–Hodgman) and returns the overlapping region.
+func IntersectConvex(subject, clip Polygon) Polygon {
+	orientation := 1.0
+	if signedArea(clip) < 0 {
+		orientation = -1
+	}
+
+	output := subject
+	for i := range clip {
+		if len(output) == 0 {
+			break
+		}
+		a, b := clip[i], clip[(i+1)%len(clip)]
+		inside := func(p Point) bool {
+			return orientation*((b.X-a.X)*(p.Y-a.Y)-(b.Y-a.Y)*(p.X-a.X)) >= 0
+		}
+
+		input := output
+		output = nil
+		for j := range input {
+			cur, prev := input[j], input[(j+len(input)-1)%len(input)]
+			if inside(cur) {
+				if !inside(prev) {
+					output = append(output, lineIntersection(prev, cur, a, b))
+				}
+				output = append(output, cur)
+			} else if inside(prev) {
+				output = append(output, lineIntersection(prev, cur, a, b))
+			}
+		}
+	}
+	return output
+}
+
+func signedArea(p Polygon) float64 {
+	var sum float64
+	for i := range p {
+		j := (i + 1) % len(p)
+		sum += p[i].X*p[j].Y - p[j].X*p[i].Y
+	}
+	return sum / 2
+}
+
+func lineIntersection(p1, p2, p3, p4 Point) Point {
+	d := (p1.X-p2.X)*(p3.Y-p4.Y) - (p1.Y-p2.Y)*(p3.X-p4.X)
+	if d == 0 {
+		return p2
+	}
+	t := ((p1.X-p3.X)*(p3.Y-p4.Y) - (p1.Y-p3.Y)*(p3.X-p4.X)) / d
+	return Point{p1.X + t*(p2.X-p1.X), p1.Y + t*(p2.Y-p1.Y)}
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func square(x, y, size float64) Polygon {
+	return Polygon{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}
+}
+
+func TestRotatedRect(t *testing.T) {
+	tests := []struct {
+		name    string
+		degrees float64
+		want    Polygon
+	}{
+		{"unrotated", 0, Polygon{{-2, -1}, {2, -1}, {2, 1}, {-2, 1}}},
+		{"quarter turn clockwise", 90, Polygon{{1, -2}, {1, 2}, {-1, 2}, {-1, -2}}},
+		{"half turn", 180, Polygon{{2, 1}, {-2, 1}, {-2, -1}, {2, -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RotatedRect(Point{}, 4, 2, tt.degrees)
+			for i := range tt.want {
+				if math.Abs(got[i].X-tt.want[i].X) > epsilon || math.Abs(got[i].Y-tt.want[i].Y) > epsilon {
+					t.Errorf("corner %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+			if area := got.Area(); math.Abs(area-8) > epsilon {
+				t.Errorf("Area() = %v, want 8", area)
+			}
+		})
+	}
+}
+
+func TestPolygonArea(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon Polygon
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"unit square", square(0, 0, 1), 1},
+		{"clockwise winding", Polygon{{0, 0}, {0, 2}, {2, 2}, {2, 0}}, 4},
+		{"triangle", Polygon{{0, 0}, {4, 0}, {0, 3}}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.polygon.Area(); math.Abs(got-tt.want) > epsilon {
+				t.Errorf("Area() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	// An L shape, to check that non-convex polygons work.
+	l := Polygon{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+
+	tests := []struct {
+		name    string
+		polygon Polygon
+		point   Point
+		want    bool
+	}{
+		{"inside square", square(0, 0, 2), Point{1, 1}, true},
+		{"outside square", square(0, 0, 2), Point{3, 1}, false},
+		{"inside L", l, Point{0.5, 1.5}, true},
+		{"in the notch of L", l, Point{1.5, 1.5}, false},
+		{"empty", nil, Point{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.polygon.Contains(tt.point); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntersectConvex(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject Polygon
+		clip    Polygon
+		want    float64
+	}{
+		{"overlapping squares", square(0, 0, 2), square(1, 1, 2), 1},
+		{"contained", square(1, 1, 1), square(0, 0, 4), 1},
+		{"containing", square(0, 0, 4), square(1, 1, 1), 1},
+		{"disjoint", square(0, 0, 1), square(5, 5, 1), 0},
+		{"clockwise clip", square(0, 0, 2), Polygon{{1, 1}, {1, 3}, {3, 3}, {3, 1}}, 1},
+		{"rotated quadrant", RotatedRect(Point{}, 2, 2, 45), square(0, -5, 5), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IntersectConvex(tt.subject, tt.clip).Area(); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("intersection area = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+func ImageDimensions(data []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+func DecodeImage(data []byte) (image.Image, string, error) {
+	return image.Decode(bytes.NewReader(data))
+}
+
+// ResizeToWidth scales img to the given width, keeping its aspect ratio.
+func ResizeToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// EncodeImage encodes img as JPEG when the source format was JPEG and as PNG
+// otherwise, so transparency survives for every other format.
+func EncodeImage(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// DifferenceHash computes the 64-bit dHash of img. Transparent areas are
+// flattened onto white so stickers with and without a background still match.
+func DifferenceHash(img image.Image) uint64 {
+	small := image.NewRGBA(image.Rect(0, 0, 9, 8))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Over, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if luminance(small, x, y) < luminance(small, x+1, y) {
+				hash |= 1 << uint(y*8+x)
+			}
+		}
+	}
+	return hash
+}
+
+func luminance(img *image.RGBA, x, y int) uint32 {
+	c := img.RGBAAt(x, y)
+	return (299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+	"testing"
+)
+
+// gradient returns an image that gets lighter to the right, or darker if
+// reversed.
+func gradient(width, height int, reversed bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(x * 255 / (width - 1))
+			if reversed {
+				v = 255 - v
+			}
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHash(t *testing.T) {
+	transparent := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+
+	tests := []struct {
+		name string
+		a, b image.Image
+		// Most bits the hashes may differ in.
+		maxDistance int
+		// Fewest bits the hashes must differ in.
+		minDistance int
+	}{
+		{"same image", gradient(64, 64, false), gradient(64, 64, false), 0, 0},
+		{"scaled copy", gradient(64, 64, false), gradient(256, 256, false), 4, 0},
+		{"mirrored", gradient(64, 64, false), gradient(64, 64, true), 64, 48},
+		{"transparent matches white", transparent, image.NewNRGBA(image.Rect(0, 0, 8, 8)), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := bits.OnesCount64(DifferenceHash(tt.a) ^ DifferenceHash(tt.b))
+			if distance > tt.maxDistance || distance < tt.minDistance {
+				t.Errorf("hashes differ in %d bits, want %d-%d", distance, tt.minDistance, tt.maxDistance)
+			}
+		})
+	}
+
+	if hash := DifferenceHash(transparent); hash != 0 {
+		t.Errorf("DifferenceHash(transparent) = %#x, want 0", hash)
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Pixels more transparent than this are ignored when extracting a palette.
+const minPaletteAlpha = 128
+
+// Images are downscaled to this width before sampling pixels.
+const paletteSampleWidth = 128
+
+type WeightedColor struct {
+	R, G, B uint8
+	Weight  float64
+}
+
+// DominantColors returns up to k dominant colors of the opaque pixels in img,
+// found by median cut. Weights are the share of opaque pixels in each color's
+// box and sum to 1. Colors are ordered by descending weight.
+func DominantColors(img image.Image, k int) []WeightedColor {
+	if img.Bounds().Dx() > paletteSampleWidth {
+		img = ResizeToWidth(img, paletteSampleWidth)
+	}
+
+	var pixels []color.NRGBA
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A >= minPaletteAlpha {
+				pixels = append(pixels, c)
+			}
+		}
+	}
+	if len(pixels) == 0 || k <= 0 {
+		return nil
+	}
+
+	boxes := [][]color.NRGBA{pixels}
+	for len(boxes) < k {
+		// Split the box with the widest channel range.
+		target, channel, widest := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			ch, r := widestChannel(box)
+			if r > widest {
+				target, channel, widest = i, ch, r
+			}
+		}
+		if target < 0 {
+			break
+		}
+
+		box := boxes[target]
+		sort.Slice(box, func(i, j int) bool {
+			return channelValue(box[i], channel) < channelValue(box[j], channel)
+		})
+		mid := len(box) / 2
+		boxes[target] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	// A median split can cut a run of identical pixels in two, so boxes that
+	// average to the same color are combined.
+	colors := make([]WeightedColor, 0, len(boxes))
+	index := make(map[[3]uint8]int, len(boxes))
+	for _, box := range boxes {
+		var r, g, b int
+		for _, c := range box {
+			r += int(c.R)
+			g += int(c.G)
+			b += int(c.B)
+		}
+		n := len(box)
+		key := [3]uint8{uint8(r / n), uint8(g / n), uint8(b / n)}
+		weight := float64(n) / float64(len(pixels))
+		if i, ok := index[key]; ok {
+			colors[i].Weight += weight
+			continue
+		}
+		index[key] = len(colors)
+		colors = append(colors, WeightedColor{R: key[0], G: key[1], B: key[2], Weight: weight})
+	}
+
+	sort.SliceStable(colors, func(i, j int) bool {
+		return colors[i].Weight > colors[j].Weight
+	})
+	return colors
+}
+
+func channelValue(c color.NRGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	default:
+		return c.B
+	}
+}
+
+func widestChannel(box []color.NRGBA) (int, int) {
+	channel, widest := 0, -1
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := uint8(255), uint8(0)
+		for _, c := range box {
+			v := channelValue(c, ch)
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if r := int(hi) - int(lo); r > widest {
+			channel, widest = ch, r
+		}
+	}
+	return channel, widest
+}
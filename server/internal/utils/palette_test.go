@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// striped returns an image whose rows are filled with the given colors, each
+// taking the given number of rows.
+func striped(width int, stripes []color.NRGBA, rows []int) image.Image {
+	height := 0
+	for _, n := range rows {
+		height += n
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	y := 0
+	for i, c := range stripes {
+		for end := y + rows[i]; y < end; y++ {
+			for x := 0; x < width; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+func TestDominantColors(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	clear := color.NRGBA{0, 255, 0, 0}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		k    int
+		want []WeightedColor
+	}{
+		{
+			name: "single color",
+			img:  striped(4, []color.NRGBA{red}, []int{4}),
+			k:    4,
+			want: []WeightedColor{{R: 255, Weight: 1}},
+		},
+		{
+			name: "two colors by weight",
+			img:  striped(4, []color.NRGBA{red, blue}, []int{1, 3}),
+			k:    4,
+			want: []WeightedColor{{B: 255, Weight: 0.75}, {R: 255, Weight: 0.25}},
+		},
+		{
+			name: "transparent pixels ignored",
+			img:  striped(4, []color.NRGBA{clear, blue}, []int{3, 1}),
+			k:    3,
+			want: []WeightedColor{{B: 255, Weight: 1}},
+		},
+		{
+			name: "fully transparent",
+			img:  striped(4, []color.NRGBA{clear}, []int{4}),
+			k:    3,
+			want: nil,
+		},
+		{
+			name: "no colors asked for",
+			img:  striped(4, []color.NRGBA{red}, []int{4}),
+			k:    0,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DominantColors(tt.img, tt.k)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DominantColors() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				g, w := got[i], tt.want[i]
+				if g.R != w.R || g.G != w.G || g.B != w.B || math.Abs(g.Weight-w.Weight) > 1e-9 {
+					t.Errorf("color %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestDominantColorsLimit(t *testing.T) {
+	stripes := make([]color.NRGBA, 16)
+	rows := make([]int, len(stripes))
+	for i := range stripes {
+		stripes[i] = color.NRGBA{uint8(i * 16), uint8(255 - i*16), 0, 255}
+		rows[i] = 1
+	}
+
+	got := DominantColors(striped(4, stripes, rows), 5)
+	if len(got) == 0 || len(got) > 5 {
+		t.Fatalf("DominantColors() returned %d colors, want 1-5", len(got))
+	}
+	var total float64
+	for i, c := range got {
+		total += c.Weight
+		if i > 0 && c.Weight > got[i-1].Weight {
+			t.Errorf("colors not ordered by weight: %v", got)
+		}
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("weights sum to %v, want 1", total)
+	}
+}